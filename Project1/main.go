@@ -2,11 +2,15 @@ package main
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -14,33 +18,389 @@ import (
 )
 
 func main() {
-	// CLI args
-	f, closeFile, err := openProcessingFile(os.Args...)
-	if err != nil {
+	if err := run(os.Args...); err != nil {
 		log.Fatal(err)
 	}
-	defer closeFile()
+}
 
-	// Load and parse processes
-	processes, err := loadProcesses(f)
+// run schedules the processes named by the command line args (binary name first) with every algorithm.
+func run(args ...string) (err error) {
+	// CLI args
+	opts, args, err := parseFlags(args...)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	out, closeOut, err := openOutputFile(opts.out)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := closeOut(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	TimeDecimals = opts.decimals
+
+	var processes []Process
+	if opts.generate > 0 {
+		scale := timeScale()
+		processes = GenerateProcesses(opts.generate, opts.seed, opts.maxBurst*scale, opts.maxArrival*scale, opts.maxPriority)
+		if opts.dump {
+			return writeProcesses(out, processes)
+		}
+	} else if processes, err = loadProcessingFile(args...); err != nil {
+		return err
+	}
+	if err := validateProcesses(processes); err != nil {
+		return err
+	}
+	if opts.check {
+		outputCheck(out, processes)
+		return nil
+	}
+
+	TieBreak = tieBreaks[opts.tieBreak]
+	HigherNumberIsHigherPriority = opts.priorityOrder == "high"
+	NewArrivalsFirst = opts.newArrivalsFirst
+	ColorGantt = opts.color && isTTY(out)
+	VerboseGantt = opts.verbose
+
+	render := Render
+	switch opts.format {
+	case "json":
+		render = outputJSON
+	case "markdown":
+		render = outputMarkdown
+	case "csv":
+		render = outputCSV
+	}
+
+	Trace = nil
+	if opts.step {
+		Trace = out
+	}
+
+	results := scheduleAll(opts, processes)
+	byTitle := make(map[string]ScheduleResult, len(results))
+	for _, r := range results {
+		render(out, r)
+		if opts.starvationThreshold > 0 && (opts.format == "table" || opts.format == "markdown") {
+			outputStarvation(out, r, opts.starvationThreshold)
+		}
+		byTitle[r.Title] = r
+	}
+
+	if len(processes) == 0 {
+		// there is nothing to compare.
+		return nil
+	}
+	switch opts.format {
+	case "table":
+		SummaryCompare(out, byTitle)
+		if opts.detailed {
+			DetailedView(out, results)
+		}
+	case "markdown":
+		SummaryCompareMarkdown(out, byTitle)
+		if opts.detailed {
+			DetailedViewMarkdown(out, results)
+		}
+	}
+
+	return nil
+}
+
+// algorithms are the schedulers run, in order, each with the name -algo selects it by.
+// With -preemptive, sjf and priority select their preemptive forms, srtf and preemptive-priority.
+var algorithms = []struct {
+	name     string
+	schedule func(opts options, processes []Process) ScheduleResult
+}{
+	{"fcfs", func(opts options, processes []Process) ScheduleResult {
+		if opts.cores > 1 {
+			return FCFSMulti(fmt.Sprintf("First-come, first-serve (%d cores)", opts.cores), processes, opts.cores, opts.switchCost)
+		}
+		return FCFS("First-come, first-serve", processes, opts.switchCost)
+	}},
+	{"sjf", func(opts options, processes []Process) ScheduleResult {
+		if opts.preemptive {
+			return SRTF("Shortest-remaining-time-first", processes, opts.switchCost)
+		}
+		return SJF("Shortest-job-first", processes, opts.switchCost)
+	}},
+	{"srtf", func(opts options, processes []Process) ScheduleResult {
+		return SRTF("Shortest-remaining-time-first", processes, opts.switchCost)
+	}},
+	{"ljf", func(opts options, processes []Process) ScheduleResult {
+		return LJF("Longest-job-first", processes, opts.switchCost)
+	}},
+	{"lrtf", func(opts options, processes []Process) ScheduleResult {
+		return LRTF("Longest-remaining-time-first", processes, opts.switchCost)
+	}},
+	{"preemptive-priority", func(opts options, processes []Process) ScheduleResult {
+		return PreemptivePriority("Preemptive priority", processes, opts.aging, opts.switchCost)
+	}},
+	{"priority", func(opts options, processes []Process) ScheduleResult {
+		if opts.preemptive {
+			return PreemptivePriority("Preemptive priority", processes, opts.aging, opts.switchCost)
+		}
+		return SJFPriority("Priority", processes, opts.aging, opts.switchCost)
+	}},
+	{"rr", func(opts options, processes []Process) ScheduleResult {
+		return RR("Round-robin", processes, opts.quantum, opts.switchCost)
+	}},
+	{"mlfq", func(opts options, processes []Process) ScheduleResult {
+		return MLFQ("Multilevel feedback queue", processes, opts.mlfqQuanta, opts.mlfqAging, opts.switchCost)
+	}},
+	{"lottery", func(opts options, processes []Process) ScheduleResult {
+		return Lottery("Lottery", processes, opts.quantum, opts.seed, opts.switchCost)
+	}},
+}
+
+// algorithmNames lists the names of the algorithms for error messages.
+func algorithmNames() string {
+	names := make([]string, len(algorithms))
+	for i := range algorithms {
+		names[i] = algorithms[i].name
+	}
+	return strings.Join(names, ", ")
+}
+
+// knownAlgorithm reports whether name is the name of one of the algorithms.
+func knownAlgorithm(name string) bool {
+	for i := range algorithms {
+		if algorithms[i].name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleAll runs the algorithms named in opts.algos in that order, or every algorithm when none are named.
+// An algorithm that schedules the same as one already run, as sjf does srtf with -preemptive, is left out.
+func scheduleAll(opts options, processes []Process) []ScheduleResult {
+	names := opts.algos
+	if len(names) == 0 {
+		for i := range algorithms {
+			names = append(names, algorithms[i].name)
+		}
+	}
+	var (
+		results = make([]ScheduleResult, 0, len(names))
+		titles  = make(map[string]bool, len(names))
+	)
+	for _, name := range names {
+		for i := range algorithms {
+			if algorithms[i].name != name {
+				continue
+			}
+			if r := algorithms[i].schedule(opts, processes); !titles[r.Title] {
+				titles[r.Title] = true
+				results = append(results, r)
+			}
+		}
+	}
+	return results
+}
+
+// options holds the settings given as command line flags.
+type options struct {
+	format     string
+	decimals   int
+	aging      int64
+	quantum    int64
+	mlfqQuanta []int64
+	mlfqAging  int64
+	switchCost int64
+	tieBreak   string
+	cores      int
+	// preemptive selects the preemptive form of the sjf and priority algorithms.
+	preemptive bool
+	// newArrivalsFirst queues processes arriving during a round-robin quantum ahead of the process it preempts.
+	newArrivalsFirst bool
+	// priorityOrder is "low" when a lower Priority number is a higher priority, or "high" for the opposite.
+	priorityOrder string
+
+	// random workload generation.
+	generate    int
+	seed        int64
+	maxBurst    int64
+	maxArrival  int64
+	maxPriority int64
+	dump        bool
+
+	out      string
+	color    bool
+	detailed bool
+	verbose  bool
+
+	starvationThreshold int64
+	check               bool
+
+	// algos are the names of the algorithms to run in order, or empty for all of them.
+	algos []string
+	step  bool
+}
+
+// parseFlags parses the command line flags from args (binary name first),
+// returning the options and the remaining positional args with the binary name still first.
+func parseFlags(args ...string) (options, []string, error) {
+	if len(args) == 0 {
+		return options{}, nil, fmt.Errorf("%w: missing binary name", ErrInvalidArgs)
+	}
+
+	// times are parsed once -decimals is known, wherever it appears on the command line.
+	var (
+		opts                                                    options
+		aging, quantum, mlfqQuanta, mlfqAging, cost, starvation string
+		algos                                                   string
+		err                                                     error
+	)
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.StringVar(&opts.format, "format", "table", "output format: table, json, markdown, or csv")
+	fs.IntVar(&opts.decimals, "decimals", 0, "number of decimals allowed in times, which are also shown with this many decimals")
+	fs.StringVar(&aging, "aging", "0", "time a process waits before its priority improves by one in the priority schedulers (0 disables)")
+	fs.StringVar(&quantum, "quantum", fmt.Sprint(defaultQuantum), "round-robin time quantum")
+	fs.StringVar(&mlfqQuanta, "mlfq-quanta", defaultMLFQQuanta, "comma separated time quantum of each multilevel feedback queue level")
+	fs.StringVar(&mlfqAging, "mlfq-aging", "0", "time a process waits before it is moved up a multilevel feedback queue level (0 disables)")
+	fs.BoolVar(&opts.preemptive, "preemptive", false, "run the preemptive form of the sjf and priority algorithms, srtf and preemptive-priority")
+	fs.BoolVar(&opts.newArrivalsFirst, "new-arrivals-first", false, "queue processes arriving during a round-robin quantum ahead of the process it preempts")
+	fs.StringVar(&cost, "switch-cost", "0", "time spent switching from one process to another, charged by every scheduler")
+	fs.StringVar(&starvation, "starvation-threshold", "0", "warn about processes that wait longer than this (0 disables)")
+	fs.StringVar(&opts.tieBreak, "tie-break", "arrival", "order of processes that tie: arrival (then priority, then PID) or pid")
+	fs.StringVar(&opts.priorityOrder, "priority-order", "low", "which Priority number wins in the priority schedulers: low or high")
+	fs.IntVar(&opts.cores, "cores", 1, "number of CPUs first-come, first-serve schedules across")
+	fs.IntVar(&opts.generate, "generate", 0, "schedule this many randomly generated processes instead of reading a file")
+	fs.Int64Var(&opts.seed, "seed", 1, "random seed for -generate")
+	fs.Int64Var(&opts.maxBurst, "max-burst", 10, "largest burst duration for -generate")
+	fs.Int64Var(&opts.maxArrival, "max-arrival", 20, "latest arrival time for -generate")
+	fs.Int64Var(&opts.maxPriority, "max-priority", 5, "largest priority for -generate")
+	fs.BoolVar(&opts.dump, "dump", false, "write the -generate processes as CSV instead of scheduling them")
+	fs.StringVar(&opts.out, "out", "", "file to write the output to, created or truncated (default or - for stdout)")
+	fs.BoolVar(&opts.color, "color", false, "color the Gantt chart by process when writing to a terminal")
+	fs.BoolVar(&opts.verbose, "verbose", false, "list the processes waiting in the ready queue as each Gantt slice started")
+	fs.BoolVar(&opts.detailed, "detailed", false, "print every process's timing under every scheduler after the summary")
+	fs.BoolVar(&opts.check, "check", false, "only load and validate the processes, printing a summary of them")
+	fs.StringVar(&algos, "algo", "", "comma separated algorithms to run in order (default all): "+algorithmNames())
+	fs.BoolVar(&opts.step, "step", false, "trace every scheduling decision of the one -algo algorithm, which must be fcfs or rr")
+	if err := fs.Parse(args[1:]); err != nil {
+		return options{}, nil, fmt.Errorf("%w: %v", ErrInvalidArgs, err)
+	}
+	if opts.format != "table" && opts.format != "json" && opts.format != "markdown" && opts.format != "csv" {
+		return options{}, nil, fmt.Errorf("%w: unknown format %q, expected table, json, markdown, or csv", ErrInvalidArgs, opts.format)
+	}
+	if opts.decimals < 0 || opts.decimals > maxDecimals {
+		return options{}, nil, fmt.Errorf("%w: decimals must be between 0 and %d, got %d", ErrInvalidArgs, maxDecimals, opts.decimals)
+	}
+	for _, t := range []struct {
+		name  string
+		value string
+		dst   *int64
+	}{
+		{"aging", aging, &opts.aging},
+		{"quantum", quantum, &opts.quantum},
+		{"mlfq-aging", mlfqAging, &opts.mlfqAging},
+		{"switch-cost", cost, &opts.switchCost},
+		{"starvation-threshold", starvation, &opts.starvationThreshold},
+	} {
+		if *t.dst, err = parseTime(t.value, opts.decimals); err != nil {
+			return options{}, nil, fmt.Errorf("%w: %s: %v", ErrInvalidArgs, t.name, err)
+		}
+	}
+	if opts.aging < 0 {
+		return options{}, nil, fmt.Errorf("%w: aging must not be negative, got %s", ErrInvalidArgs, aging)
+	}
+	if opts.quantum <= 0 {
+		return options{}, nil, fmt.Errorf("%w: quantum must be positive, got %s", ErrInvalidArgs, quantum)
+	}
+	if opts.mlfqQuanta, err = parseQuanta(mlfqQuanta, opts.decimals); err != nil {
+		return options{}, nil, fmt.Errorf("%w: mlfq-quanta: %v", ErrInvalidArgs, err)
+	}
+	if opts.mlfqAging < 0 {
+		return options{}, nil, fmt.Errorf("%w: mlfq-aging must not be negative, got %s", ErrInvalidArgs, mlfqAging)
+	}
+	if opts.switchCost < 0 {
+		return options{}, nil, fmt.Errorf("%w: switch-cost must not be negative, got %s", ErrInvalidArgs, cost)
+	}
+	if opts.starvationThreshold < 0 {
+		return options{}, nil, fmt.Errorf("%w: starvation-threshold must not be negative, got %s", ErrInvalidArgs, starvation)
+	}
+	if opts.priorityOrder != "low" && opts.priorityOrder != "high" {
+		return options{}, nil, fmt.Errorf("%w: unknown priority-order %q, expected low or high", ErrInvalidArgs, opts.priorityOrder)
+	}
+	if opts.cores <= 0 {
+		return options{}, nil, fmt.Errorf("%w: cores must be positive, got %d", ErrInvalidArgs, opts.cores)
+	}
+	if opts.generate < 0 {
+		return options{}, nil, fmt.Errorf("%w: generate must not be negative, got %d", ErrInvalidArgs, opts.generate)
+	}
+	if opts.generate > 0 && (opts.maxBurst <= 0 || opts.maxArrival < 0 || opts.maxPriority <= 0) {
+		return options{}, nil, fmt.Errorf("%w: max-burst and max-priority must be positive and max-arrival not negative", ErrInvalidArgs)
+	}
+	if opts.dump && opts.generate == 0 {
+		return options{}, nil, fmt.Errorf("%w: dump requires generate", ErrInvalidArgs)
+	}
+	if _, ok := tieBreaks[opts.tieBreak]; !ok {
+		return options{}, nil, fmt.Errorf("%w: unknown tie-break %q, expected arrival or pid", ErrInvalidArgs, opts.tieBreak)
+	}
+	if algos != "" {
+		for _, name := range strings.Split(algos, ",") {
+			name = strings.TrimSpace(name)
+			if !knownAlgorithm(name) {
+				return options{}, nil, fmt.Errorf("%w: unknown algo %q, expected one of %s", ErrInvalidArgs, name, algorithmNames())
+			}
+			opts.algos = append(opts.algos, name)
+		}
+	}
+	if opts.step && (len(opts.algos) != 1 || opts.algos[0] != "fcfs" && opts.algos[0] != "rr") {
+		return options{}, nil, fmt.Errorf("%w: step requires algo fcfs or rr alone", ErrInvalidArgs)
+	}
+	if opts.step && opts.cores > 1 {
+		return options{}, nil, fmt.Errorf("%w: step requires a single core", ErrInvalidArgs)
+	}
+
+	return opts, append([]string{args[0]}, fs.Args()...), nil
+}
+
+// parseQuanta parses a comma separated list of positive time quanta with up to decimals decimals.
+func parseQuanta(s string, decimals int) ([]int64, error) {
+	fields := strings.Split(s, ",")
+	quanta := make([]int64, len(fields))
+	for i := range fields {
+		q, err := parseTime(fields[i], decimals)
+		if err != nil {
+			return nil, err
+		}
+		if q <= 0 {
+			return nil, fmt.Errorf("quantum must be positive, got %s", strings.TrimSpace(fields[i]))
+		}
+		quanta[i] = q
 	}
 
-	// First-come, first-serve scheduling
-	FCFSSchedule(os.Stdout, "First-come, first-serve", processes)
+	return quanta, nil
+}
+
+// loadProcessingFile loads the processes from the scheduling file named by args[1].
+func loadProcessingFile(args ...string) ([]Process, error) {
+	f, closeFile, err := openProcessingFile(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
 
-	//SJFSchedule(os.Stdout, "Shortest-job-first", processes)
-	//
-	//SJFPrioritySchedule(os.Stdout, "Priority", processes)
-	//
-	//RRSchedule(os.Stdout, "Round-robin", processes)
+	return loadProcesses(f)
 }
 
+// openProcessingFile opens the scheduling file named by args[1], where "-" reads from stdin.
 func openProcessingFile(args ...string) (*os.File, func(), error) {
 	if len(args) != 2 {
 		return nil, nil, fmt.Errorf("%w: must give a scheduling file to process", ErrInvalidArgs)
 	}
+	if args[1] == "-" {
+		// stdin belongs to the process, so it is left open.
+		return os.Stdin, func() {}, nil
+	}
 	// Read in CSV process CSV file
 	f, err := os.Open(args[1])
 	if err != nil {
@@ -55,6 +415,26 @@ func openProcessingFile(args ...string) (*os.File, func(), error) {
 	return f, closeFn, nil
 }
 
+// openOutputFile creates or truncates the output file at path, where "" or "-" is stdout.
+func openOutputFile(path string) (*os.File, func() error, error) {
+	if path == "" || path == "-" {
+		// stdout belongs to the process, so it is left open.
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: error creating output file", err)
+	}
+	closeFn := func() error {
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("%v: error closing output file", err)
+		}
+		return nil
+	}
+
+	return f, closeFn, nil
+}
+
 type (
 	Process struct {
 		ProcessID     int64
@@ -63,10 +443,50 @@ type (
 		Priority      int64
 	}
 	TimeSlice struct {
-		PID   int64
-		Start int64
-		Stop  int64
+		PID   int64 `json:"pid"`
+		Start int64 `json:"start"`
+		Stop  int64 `json:"stop"`
+		// Level is the 1-based feedback queue the slice ran at, or 0 for schedulers without levels.
+		Level int `json:"level,omitempty"`
+		// Core is the 1-based CPU the slice ran on, or 0 for single core schedulers.
+		Core int `json:"core,omitempty"`
 	}
+	// ProcessStats is the timing of a single process in a schedule.
+	ProcessStats struct {
+		Process
+		Wait       int64
+		Turnaround int64
+		Response   int64
+		Exit       int64
+	}
+	// ScheduleResult is the outcome of a scheduler run, ready to be rendered.
+	ScheduleResult struct {
+		Title         string
+		Gantt         []TimeSlice
+		Rows          [][]string
+		Stats         []ProcessStats
+		AvgWait       float64
+		AvgTurnaround float64
+		AvgResponse   float64
+		// AvgNormalizedTurnaround is the average of each process's turnaround divided by its burst duration,
+		// which compares how fairly processes of different sizes were treated.
+		AvgNormalizedTurnaround float64
+		// Throughput is the processes completed per time unit of the makespan,
+		// so a late first arrival does not count against any algorithm.
+		Throughput  float64
+		Utilization float64
+		// Makespan is the time from the first arrival to the last completion,
+		// and Idle is how much of it the CPUs spent without a process to run.
+		Makespan int64
+		Idle     int64
+	}
+)
+
+const (
+	// IdlePID marks a TimeSlice where the CPU had no process to run.
+	IdlePID int64 = -1
+	// OverheadPID marks a TimeSlice spent switching between processes.
+	OverheadPID int64 = -2
 )
 
 //region Schedulers
@@ -75,38 +495,57 @@ type (
 // • an output writer
 // • a title for the chart
 // • a slice of processes
-func FCFSSchedule(w io.Writer, title string, processes []Process) {
+// • a context switch cost, or 0 to switch for free
+func FCFSSchedule(w io.Writer, title string, processes []Process, contextSwitchCost int64) {
+	Render(w, FCFS(title, processes, contextSwitchCost))
+}
+
+// FCFS schedules processes first-come, first-serve in order of arrival, with TieBreak ordering those that arrive together.
+func FCFS(title string, processes []Process, contextSwitchCost int64) ScheduleResult {
+	// sort a private copy so the caller's processes are left untouched.
+	processes = append([]Process(nil), processes...)
+	sort.SliceStable(processes, func(i, j int) bool {
+		return byArrival(processes[i], processes[j])
+	})
+
 	var (
-		serviceTime     int64
-		totalWait       float64
-		totalTurnaround float64
-		lastCompletion  float64
-		waitingTime     int64
-		schedule        = make([][]string, len(processes))
-		gantt           = make([]TimeSlice, 0)
+		serviceTime int64
+		waitingTime int64
+		stats       = make([]ProcessStats, len(processes))
+		gantt       = make([]TimeSlice, 0)
 	)
 	for i := range processes {
-		if processes[i].ArrivalTime > 0 {
-			waitingTime = serviceTime - processes[i].ArrivalTime
+		if processes[i].ArrivalTime > serviceTime {
+			// the CPU sits idle until the process arrives.
+			traceStep(serviceTime, nil, IdlePID)
+			gantt = append(gantt, TimeSlice{
+				PID:   IdlePID,
+				Start: serviceTime,
+				Stop:  processes[i].ArrivalTime,
+			})
+			serviceTime = processes[i].ArrivalTime
+		}
+		gantt, serviceTime = contextSwitch(gantt, processes[i].ProcessID, serviceTime, contextSwitchCost)
+		// serviceTime has been advanced to the arrival if the CPU was idle, so the wait is never negative.
+		waitingTime = serviceTime - processes[i].ArrivalTime
+		start := serviceTime
+		if Trace != nil {
+			var ready []int64
+			for _, p := range processes[i:] {
+				if p.ArrivalTime <= start {
+					ready = append(ready, p.ProcessID)
+				}
+			}
+			traceStep(start, ready, processes[i].ProcessID)
 		}
-		totalWait += float64(waitingTime)
-
-		start := waitingTime + processes[i].ArrivalTime
-
-		turnaround := processes[i].BurstDuration + waitingTime
-		totalTurnaround += float64(turnaround)
-
-		completion := processes[i].BurstDuration + processes[i].ArrivalTime + waitingTime
-		lastCompletion = float64(completion)
 
-		schedule[i] = []string{
-			fmt.Sprint(processes[i].ProcessID),
-			fmt.Sprint(processes[i].Priority),
-			fmt.Sprint(processes[i].BurstDuration),
-			fmt.Sprint(processes[i].ArrivalTime),
-			fmt.Sprint(waitingTime),
-			fmt.Sprint(turnaround),
-			fmt.Sprint(completion),
+		stats[i] = ProcessStats{
+			Process:    processes[i],
+			Wait:       waitingTime,
+			Turnaround: processes[i].BurstDuration + waitingTime,
+			// a process runs to completion once dispatched, so its response is its first (and only) start.
+			Response: start - processes[i].ArrivalTime,
+			Exit:     processes[i].BurstDuration + processes[i].ArrivalTime + waitingTime,
 		}
 		serviceTime += processes[i].BurstDuration
 
@@ -117,95 +556,1897 @@ func FCFSSchedule(w io.Writer, title string, processes []Process) {
 		})
 	}
 
-	count := float64(len(processes))
-	aveWait := totalWait / count
-	aveTurnaround := totalTurnaround / count
-	aveThroughput := count / lastCompletion
+	return newScheduleResult(title, stats, gantt)
+}
 
-	outputTitle(w, title)
-	outputGantt(w, gantt)
-	outputSchedule(w, schedule, aveWait, aveTurnaround, aveThroughput)
+// FCFSScheduleMulti outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes
+// • the number of cores, which must be positive
+// • a context switch cost, or 0 to switch for free
+func FCFSScheduleMulti(w io.Writer, title string, processes []Process, cores int, contextSwitchCost int64) {
+	Render(w, FCFSMulti(title, processes, cores, contextSwitchCost))
 }
 
-//func SJFPrioritySchedule(w io.Writer, title string, processes []Process) { }
-//
-//func SJFSchedule(w io.Writer, title string, processes []Process) { }
-//
-//func RRSchedule(w io.Writer, title string, processes []Process) { }
+// FCFSMulti schedules processes first-come, first-serve in order of arrival, with ties broken by TieBreak, across cores parallel CPUs.
+// Each process is assigned to the first core to become free, the lowest numbered core winning a tie,
+// and runs there to completion. A core that runs a process straight after another pays the context switch cost first.
+func FCFSMulti(title string, processes []Process, cores int, contextSwitchCost int64) ScheduleResult {
+	// sort a private copy so the caller's processes are left untouched.
+	local := append([]Process(nil), processes...)
+	sort.SliceStable(local, func(i, j int) bool {
+		return byArrival(local[i], local[j])
+	})
 
-//endregion
+	var (
+		free       = make([]int64, cores) // time each core finishes its current process.
+		busy       = make([]bool, cores)  // whether each core last ran a process rather than idling.
+		completion = make([]int64, len(local))
+		gantt      = make([]TimeSlice, 0)
+	)
+	for i := range local {
+		core := 0
+		for c := range free {
+			if free[c] < free[core] {
+				core = c
+			}
+		}
+		if local[i].ArrivalTime > free[core] {
+			// the core sits idle until the process arrives.
+			gantt = append(gantt, TimeSlice{
+				PID:   IdlePID,
+				Start: free[core],
+				Stop:  local[i].ArrivalTime,
+				Core:  core + 1,
+			})
+			free[core] = local[i].ArrivalTime
+			busy[core] = false
+		}
+		if busy[core] && contextSwitchCost > 0 {
+			gantt = append(gantt, TimeSlice{
+				PID:   OverheadPID,
+				Start: free[core],
+				Stop:  free[core] + contextSwitchCost,
+				Core:  core + 1,
+			})
+			free[core] += contextSwitchCost
+		}
+		busy[core] = true
 
-//region Output helpers
+		gantt = append(gantt, TimeSlice{
+			PID:   local[i].ProcessID,
+			Start: free[core],
+			Stop:  free[core] + local[i].BurstDuration,
+			Core:  core + 1,
+		})
+		free[core] += local[i].BurstDuration
+		completion[i] = free[core]
+	}
 
-func outputTitle(w io.Writer, title string) {
-	_, _ = fmt.Fprintln(w, strings.Repeat("-", len(title)*2))
-	_, _ = fmt.Fprintln(w, strings.Repeat(" ", len(title)/2), title)
-	_, _ = fmt.Fprintln(w, strings.Repeat("-", len(title)*2))
+	return newScheduleResult(title, processStats(local, completion, gantt), gantt)
 }
 
-func outputGantt(w io.Writer, gantt []TimeSlice) {
-	_, _ = fmt.Fprintln(w, "Gantt schedule")
-	_, _ = fmt.Fprint(w, "|")
-	for i := range gantt {
-		pid := fmt.Sprint(gantt[i].PID)
-		padding := strings.Repeat(" ", (8-len(pid))/2)
-		_, _ = fmt.Fprint(w, padding, pid, padding, "|")
+// SJFSchedule outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes
+// • a context switch cost, or 0 to switch for free
+func SJFSchedule(w io.Writer, title string, processes []Process, contextSwitchCost int64) {
+	Render(w, SJF(title, processes, contextSwitchCost))
+}
+
+// SJF schedules processes shortest-job-first.
+// Scheduling is non-preemptive: once the shortest available job is dispatched it runs to completion.
+// Ties are broken by TieBreak.
+func SJF(title string, processes []Process, contextSwitchCost int64) ScheduleResult {
+	var (
+		currentTime int64
+		completed   int
+		done        = make([]bool, len(processes))
+		completion  = make([]int64, len(processes))
+		gantt       = make([]TimeSlice, 0)
+	)
+	for completed < len(processes) {
+		shortest := -1
+		for i := range processes {
+			if done[i] || processes[i].ArrivalTime > currentTime {
+				continue
+			}
+			if shortest == -1 || shorterJob(processes[i], processes[i].BurstDuration, processes[shortest], processes[shortest].BurstDuration) {
+				shortest = i
+			}
+		}
+		if shortest == -1 {
+			// nothing has arrived yet, so idle until the next arrival.
+			next := nextArrival(processes, done)
+			gantt = append(gantt, TimeSlice{
+				PID:   IdlePID,
+				Start: currentTime,
+				Stop:  next,
+			})
+			currentTime = next
+			continue
+		}
+
+		gantt, currentTime = contextSwitch(gantt, processes[shortest].ProcessID, currentTime, contextSwitchCost)
+		gantt = append(gantt, TimeSlice{
+			PID:   processes[shortest].ProcessID,
+			Start: currentTime,
+			Stop:  currentTime + processes[shortest].BurstDuration,
+		})
+		currentTime += processes[shortest].BurstDuration
+		completion[shortest] = currentTime
+		done[shortest] = true
+		completed++
 	}
-	_, _ = fmt.Fprintln(w)
-	for i := range gantt {
-		_, _ = fmt.Fprint(w, fmt.Sprint(gantt[i].Start), "\t")
-		if len(gantt)-1 == i {
-			_, _ = fmt.Fprint(w, fmt.Sprint(gantt[i].Stop))
+
+	return newScheduleResult(title, processStats(processes, completion, gantt), gantt)
+}
+
+// SRTFSchedule outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes
+// • a context switch cost, or 0 to switch for free
+func SRTFSchedule(w io.Writer, title string, processes []Process, contextSwitchCost int64) {
+	Render(w, SRTF(title, processes, contextSwitchCost))
+}
+
+// SRTF schedules processes shortest-remaining-time-first.
+// Scheduling is preemptive: at any time the job with the shortest remaining burst is run.
+// Ties are broken by TieBreak.
+// Decisions are only made when a process arrives or completes, since the running job's remaining burst
+// only shrinks in between and so it cannot be overtaken.
+func SRTF(title string, processes []Process, contextSwitchCost int64) ScheduleResult {
+	var (
+		currentTime     int64
+		completed       int
+		remainingBursts = make([]int64, len(processes))
+		completion      = make([]int64, len(processes))
+		gantt           = make([]TimeSlice, 0)
+	)
+	for i := range processes {
+		remainingBursts[i] = processes[i].BurstDuration
+	}
+	for completed < len(processes) {
+		shortest := -1
+		next := int64(-1) // the earliest arrival still to come, which may preempt.
+		for i := range processes {
+			if remainingBursts[i] == 0 {
+				continue
+			}
+			if processes[i].ArrivalTime > currentTime {
+				if next == -1 || processes[i].ArrivalTime < next {
+					next = processes[i].ArrivalTime
+				}
+				continue
+			}
+			if shortest == -1 || shorterJob(processes[i], remainingBursts[i], processes[shortest], remainingBursts[shortest]) {
+				shortest = i
+			}
+		}
+		if shortest == -1 {
+			// nothing has arrived yet, so idle until the next arrival.
+			gantt = appendGantt(gantt, IdlePID, currentTime, next)
+			currentTime = next
+			continue
+		}
+
+		gantt, currentTime = contextSwitch(gantt, processes[shortest].ProcessID, currentTime, contextSwitchCost)
+		stop := currentTime + remainingBursts[shortest]
+		if next != -1 && next < stop {
+			// processes arriving during a context switch are only considered once the dispatched job has run for a time unit.
+			stop = next
+			if stop <= currentTime {
+				stop = currentTime + 1
+			}
+		}
+		gantt = appendGantt(gantt, processes[shortest].ProcessID, currentTime, stop)
+		remainingBursts[shortest] -= stop - currentTime
+		currentTime = stop
+
+		if remainingBursts[shortest] == 0 {
+			completion[shortest] = currentTime
+			completed++
 		}
 	}
-	_, _ = fmt.Fprintf(w, "\n\n")
+
+	return newScheduleResult(title, processStats(processes, completion, gantt), gantt)
 }
 
-func outputSchedule(w io.Writer, rows [][]string, wait, turnaround, throughput float64) {
-	_, _ = fmt.Fprintln(w, "Schedule table")
-	table := tablewriter.NewWriter(w)
-	table.SetHeader([]string{"ID", "Priority", "Burst", "Arrival", "Wait", "Turnaround", "Exit"})
-	table.AppendBulk(rows)
-	table.SetFooter([]string{"", "", "", "",
-		fmt.Sprintf("Average\n%.2f", wait),
-		fmt.Sprintf("Average\n%.2f", turnaround),
-		fmt.Sprintf("Throughput\n%.2f/t", throughput)})
-	table.Render()
+// LJFSchedule outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes
+// • a context switch cost, or 0 to switch for free
+func LJFSchedule(w io.Writer, title string, processes []Process, contextSwitchCost int64) {
+	Render(w, LJF(title, processes, contextSwitchCost))
 }
 
-//endregion
+// LJF schedules processes longest-job-first.
+// Scheduling is non-preemptive: once the longest available job is dispatched it runs to completion.
+// Ties are broken by TieBreak.
+func LJF(title string, processes []Process, contextSwitchCost int64) ScheduleResult {
+	var (
+		currentTime int64
+		completed   int
+		done        = make([]bool, len(processes))
+		completion  = make([]int64, len(processes))
+		gantt       = make([]TimeSlice, 0)
+	)
+	for completed < len(processes) {
+		longest := -1
+		for i := range processes {
+			if done[i] || processes[i].ArrivalTime > currentTime {
+				continue
+			}
+			if longest == -1 || longerJob(processes[i], processes[i].BurstDuration, processes[longest], processes[longest].BurstDuration) {
+				longest = i
+			}
+		}
+		if longest == -1 {
+			// nothing has arrived yet, so idle until the next arrival.
+			next := nextArrival(processes, done)
+			gantt = append(gantt, TimeSlice{
+				PID:   IdlePID,
+				Start: currentTime,
+				Stop:  next,
+			})
+			currentTime = next
+			continue
+		}
 
-//region Loading processes.
+		gantt, currentTime = contextSwitch(gantt, processes[longest].ProcessID, currentTime, contextSwitchCost)
+		gantt = append(gantt, TimeSlice{
+			PID:   processes[longest].ProcessID,
+			Start: currentTime,
+			Stop:  currentTime + processes[longest].BurstDuration,
+		})
+		currentTime += processes[longest].BurstDuration
+		completion[longest] = currentTime
+		done[longest] = true
+		completed++
+	}
+
+	return newScheduleResult(title, processStats(processes, completion, gantt), gantt)
+}
 
-var ErrInvalidArgs = errors.New("invalid args")
+// LRTFSchedule outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes
+// • a context switch cost, or 0 to switch for free
+func LRTFSchedule(w io.Writer, title string, processes []Process, contextSwitchCost int64) {
+	Render(w, LRTF(title, processes, contextSwitchCost))
+}
 
-func loadProcesses(r io.Reader) ([]Process, error) {
-	rows, err := csv.NewReader(r).ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("%w: reading CSV", err)
+// LRTF schedules processes longest-remaining-time-first.
+// Scheduling is preemptive: every time unit the job with the longest remaining burst is run.
+// Ties are broken by TieBreak.
+// The longest job runs until a process arrives or it is no longer ahead of the next longest,
+// after which the jobs tied for the longest take turns a time unit at a time.
+func LRTF(title string, processes []Process, contextSwitchCost int64) ScheduleResult {
+	var (
+		currentTime     int64
+		completed       int
+		unit            = timeScale()
+		remainingBursts = make([]int64, len(processes))
+		completion      = make([]int64, len(processes))
+		gantt           = make([]TimeSlice, 0)
+	)
+	for i := range processes {
+		remainingBursts[i] = processes[i].BurstDuration
 	}
+	for completed < len(processes) {
+		longest, second := -1, -1
+		next := int64(-1) // the earliest arrival still to come, which may preempt.
+		for i := range processes {
+			if remainingBursts[i] == 0 {
+				continue
+			}
+			if processes[i].ArrivalTime > currentTime {
+				if next == -1 || processes[i].ArrivalTime < next {
+					next = processes[i].ArrivalTime
+				}
+				continue
+			}
+			if longest == -1 || longerJob(processes[i], remainingBursts[i], processes[longest], remainingBursts[longest]) {
+				longest, second = i, longest
+			} else if second == -1 || longerJob(processes[i], remainingBursts[i], processes[second], remainingBursts[second]) {
+				second = i
+			}
+		}
+		if longest == -1 {
+			// nothing has arrived yet, so idle until the next arrival.
+			gantt = appendGantt(gantt, IdlePID, currentTime, next)
+			currentTime = next
+			continue
+		}
 
-	processes := make([]Process, len(rows))
-	for i := range rows {
-		processes[i].ProcessID = mustStrToInt(rows[i][0])
-		processes[i].BurstDuration = mustStrToInt(rows[i][1])
-		processes[i].ArrivalTime = mustStrToInt(rows[i][2])
-		if len(rows[i]) == 4 {
-			processes[i].Priority = mustStrToInt(rows[i][3])
+		gantt, currentTime = contextSwitch(gantt, processes[longest].ProcessID, currentTime, contextSwitchCost)
+		run := remainingBursts[longest]
+		if second != -1 {
+			if lead := remainingBursts[longest] - remainingBursts[second]; lead > 0 {
+				run = lead
+			} else if unit < run {
+				run = unit
+			}
+		}
+		stop := currentTime + run
+		if next != -1 && next < stop {
+			// processes arriving during a context switch are only considered once the dispatched job has run for a tick.
+			stop = next
+			if stop <= currentTime {
+				stop = currentTime + 1
+			}
+		}
+		gantt = appendGantt(gantt, processes[longest].ProcessID, currentTime, stop)
+		remainingBursts[longest] -= stop - currentTime
+		currentTime = stop
+
+		if remainingBursts[longest] == 0 {
+			completion[longest] = currentTime
+			completed++
 		}
 	}
 
-	return processes, nil
+	return newScheduleResult(title, processStats(processes, completion, gantt), gantt)
 }
 
-func mustStrToInt(s string) int64 {
-	i, err := strconv.ParseInt(s, 10, 64)
-	if err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+// PreemptivePrioritySchedule outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes
+// • an aging interval, or 0 to disable aging
+// • a context switch cost, or 0 to switch for free
+func PreemptivePrioritySchedule(w io.Writer, title string, processes []Process, agingInterval, contextSwitchCost int64) {
+	Render(w, PreemptivePriority(title, processes, agingInterval, contextSwitchCost))
+}
+
+// PreemptivePriority schedules processes by priority, preempting the running process.
+// Every time unit the highest priority arrived process is run, where a lower Priority number is a higher priority
+// unless HigherNumberIsHigherPriority is set.
+// Ties are broken by TieBreak. When agingInterval is not 0,
+// a process's priority improves by one for every agingInterval time units it has spent waiting.
+// Decisions are only made when a process arrives or completes or a waiting process's priority improves,
+// since the choice cannot change in between.
+func PreemptivePriority(title string, processes []Process, agingInterval, contextSwitchCost int64) ScheduleResult {
+	var (
+		currentTime     int64
+		completed       int
+		remainingBursts = make([]int64, len(processes))
+		waited          = make([]int64, len(processes))
+		completion      = make([]int64, len(processes))
+		gantt           = make([]TimeSlice, 0)
+	)
+	for i := range processes {
+		remainingBursts[i] = processes[i].BurstDuration
+	}
+	for completed < len(processes) {
+		highest := -1
+		next := int64(-1) // the earliest arrival still to come, which may preempt.
+		for i := range processes {
+			if remainingBursts[i] == 0 {
+				continue
+			}
+			if processes[i].ArrivalTime > currentTime {
+				if next == -1 || processes[i].ArrivalTime < next {
+					next = processes[i].ArrivalTime
+				}
+				continue
+			}
+			if highest == -1 || higherPriority(
+				agedPriority(processes[i], waited[i], agingInterval),
+				agedPriority(processes[highest], waited[highest], agingInterval),
+			) {
+				highest = i
+			}
+		}
+		if highest == -1 {
+			// nothing has arrived yet, so idle until the next arrival.
+			gantt = appendGantt(gantt, IdlePID, currentTime, next)
+			currentTime = next
+			continue
+		}
+
+		// the processes that were ready also wait through any context switch.
+		dispatched := currentTime
+		gantt, currentTime = contextSwitch(gantt, processes[highest].ProcessID, currentTime, contextSwitchCost)
+		stop := currentTime + remainingBursts[highest]
+		if next != -1 && next < stop {
+			stop = next
+		}
+		for i := range processes {
+			if agingInterval > 0 && i != highest && remainingBursts[i] > 0 && processes[i].ArrivalTime <= dispatched {
+				// when the time it has waited reaches the next multiple of agingInterval.
+				if improves := dispatched + agingInterval*(waited[i]/agingInterval+1) - waited[i]; improves < stop {
+					stop = improves
+				}
+			}
+		}
+		if stop <= currentTime {
+			// what happened during a context switch is only considered once the dispatched job has run for a tick.
+			stop = currentTime + 1
+		}
+		for i := range processes {
+			if i != highest && remainingBursts[i] > 0 && processes[i].ArrivalTime <= dispatched {
+				waited[i] += stop - dispatched
+			}
+		}
+		gantt = appendGantt(gantt, processes[highest].ProcessID, currentTime, stop)
+		remainingBursts[highest] -= stop - currentTime
+		currentTime = stop
+
+		if remainingBursts[highest] == 0 {
+			completion[highest] = currentTime
+			completed++
+		}
+	}
+
+	return newScheduleResult(title, processStats(processes, completion, gantt), gantt)
+}
+
+// SJFPrioritySchedule outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes
+// • an aging interval, or 0 to disable aging
+// • a context switch cost, or 0 to switch for free
+func SJFPrioritySchedule(w io.Writer, title string, processes []Process, agingInterval, contextSwitchCost int64) {
+	Render(w, SJFPriority(title, processes, agingInterval, contextSwitchCost))
+}
+
+// SJFPriority schedules processes by priority without preemption.
+// Scheduling is non-preemptive: the highest priority arrived process runs to completion,
+// where a lower Priority number is a higher priority unless HigherNumberIsHigherPriority is set.
+// Ties are broken by the shorter burst duration, then TieBreak.
+// When agingInterval is not 0, a process's priority improves by one for every agingInterval time units it has waited.
+func SJFPriority(title string, processes []Process, agingInterval, contextSwitchCost int64) ScheduleResult {
+	var (
+		currentTime int64
+		completed   int
+		done        = make([]bool, len(processes))
+		completion  = make([]int64, len(processes))
+		gantt       = make([]TimeSlice, 0)
+	)
+	for completed < len(processes) {
+		highest := -1
+		for i := range processes {
+			if done[i] || processes[i].ArrivalTime > currentTime {
+				continue
+			}
+			if highest == -1 {
+				highest = i
+				continue
+			}
+			p := agedPriority(processes[i], currentTime-processes[i].ArrivalTime, agingInterval)
+			h := agedPriority(processes[highest], currentTime-processes[highest].ArrivalTime, agingInterval)
+			if morePriority(p.Priority, h.Priority) || (p.Priority == h.Priority && shorterJob(p, p.BurstDuration, h, h.BurstDuration)) {
+				highest = i
+			}
+		}
+		if highest == -1 {
+			// nothing has arrived yet, so idle until the next arrival.
+			next := nextArrival(processes, done)
+			gantt = append(gantt, TimeSlice{
+				PID:   IdlePID,
+				Start: currentTime,
+				Stop:  next,
+			})
+			currentTime = next
+			continue
+		}
+
+		gantt, currentTime = contextSwitch(gantt, processes[highest].ProcessID, currentTime, contextSwitchCost)
+		gantt = append(gantt, TimeSlice{
+			PID:   processes[highest].ProcessID,
+			Start: currentTime,
+			Stop:  currentTime + processes[highest].BurstDuration,
+		})
+		currentTime += processes[highest].BurstDuration
+		completion[highest] = currentTime
+		done[highest] = true
+		completed++
 	}
 
-	return i
+	return newScheduleResult(title, processStats(processes, completion, gantt), gantt)
+}
+
+// defaultQuantum is the round-robin time quantum used when none is given on the command line.
+const defaultQuantum int64 = 4
+
+// RRSchedule outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes
+// • a time quantum, which must be positive
+// • a context switch cost, or 0 to switch for free
+func RRSchedule(w io.Writer, title string, processes []Process, quantum, contextSwitchCost int64) {
+	Render(w, RR(title, processes, quantum, contextSwitchCost))
+}
+
+// NewArrivalsFirst selects where RR queues the processes that arrive while another runs its quantum,
+// including ones arriving just as it ends. When false, as by default, the preempted process is queued first,
+// as if it rejoined the queue the moment it was preempted and the arrivals were only admitted at the next dispatch.
+// When true the arrivals are queued first, the convention of most textbook exercises,
+// where a process preempted at time t goes behind everything that has arrived by t.
+var NewArrivalsFirst bool
+
+// RR schedules processes round-robin.
+// Processes are serviced in arrival order, with ties broken by TieBreak, for at most quantum time units before being re-queued,
+// behind or ahead of the arrivals during their quantum as set by NewArrivalsFirst.
+func RR(title string, processes []Process, quantum, contextSwitchCost int64) ScheduleResult {
+	// sort a private copy so the caller's processes are left untouched.
+	local := append([]Process(nil), processes...)
+	sort.SliceStable(local, func(i, j int) bool {
+		return byArrival(local[i], local[j])
+	})
+
+	var (
+		currentTime     int64
+		completed       int
+		nextToAdmit     int
+		queue           = make([]int, 0, len(local))
+		remainingBursts = make([]int64, len(local))
+		completion      = make([]int64, len(local))
+		gantt           = make([]TimeSlice, 0)
+	)
+	for i := range local {
+		remainingBursts[i] = local[i].BurstDuration
+	}
+	for completed < len(local) {
+		// admit everything that has arrived by now.
+		for nextToAdmit < len(local) && local[nextToAdmit].ArrivalTime <= currentTime {
+			queue = append(queue, nextToAdmit)
+			nextToAdmit++
+		}
+		if len(queue) == 0 {
+			// nothing has arrived yet, so idle until the next arrival.
+			traceStep(currentTime, nil, IdlePID)
+			gantt = append(gantt, TimeSlice{
+				PID:   IdlePID,
+				Start: currentTime,
+				Stop:  local[nextToAdmit].ArrivalTime,
+			})
+			currentTime = local[nextToAdmit].ArrivalTime
+			continue
+		}
+
+		if Trace != nil {
+			ready := make([]int64, len(queue))
+			for j := range queue {
+				ready[j] = local[queue[j]].ProcessID
+			}
+			traceStep(currentTime, ready, local[queue[0]].ProcessID)
+		}
+		i := queue[0]
+		queue = queue[1:]
+		gantt, currentTime = contextSwitch(gantt, local[i].ProcessID, currentTime, contextSwitchCost)
+		run := quantum
+		if remainingBursts[i] < run {
+			run = remainingBursts[i]
+		}
+		gantt = append(gantt, TimeSlice{
+			PID:   local[i].ProcessID,
+			Start: currentTime,
+			Stop:  currentTime + run,
+		})
+		currentTime += run
+		remainingBursts[i] -= run
+
+		if remainingBursts[i] == 0 {
+			completion[i] = currentTime
+			completed++
+			continue
+		}
+		if NewArrivalsFirst {
+			for nextToAdmit < len(local) && local[nextToAdmit].ArrivalTime <= currentTime {
+				queue = append(queue, nextToAdmit)
+				nextToAdmit++
+			}
+		}
+		queue = append(queue, i)
+	}
+
+	return newScheduleResult(title, processStats(local, completion, gantt), gantt)
+}
+
+// Trace, when set, is written a line for every scheduling decision FCFS and RR make:
+// the time, the PIDs ready to run in queue order, and the process dispatched.
+var Trace io.Writer
+
+// traceStep writes a scheduling decision to Trace, if set. A chosen IdlePID means the CPU idles.
+func traceStep(now int64, ready []int64, chosen int64) {
+	if Trace == nil {
+		return
+	}
+	pids := make([]string, len(ready))
+	for i := range ready {
+		pids[i] = fmt.Sprint(ready[i])
+	}
+	decision := "idle"
+	if chosen != IdlePID {
+		decision = fmt.Sprintf("run PID %d", chosen)
+	}
+	_, _ = fmt.Fprintf(Trace, "t=%s ready=[%s] %s\n", formatTime(now), strings.Join(pids, " "), decision)
+}
+
+// TieBreak reports whether a should be run before b when a scheduler's own criterion,
+// such as burst duration or priority, ranks them equally.
+// It is arrivalPriorityPID unless replaced to experiment with another policy.
+var TieBreak = arrivalPriorityPID
+
+// tieBreaks are the tie-break policies that can be chosen on the command line.
+var tieBreaks = map[string]func(a, b Process) bool{
+	"arrival": arrivalPriorityPID,
+	"pid":     lowerPID,
+}
+
+// arrivalPriorityPID orders processes by earlier arrival, then higher priority, then lower PID.
+func arrivalPriorityPID(a, b Process) bool {
+	if a.ArrivalTime != b.ArrivalTime {
+		return a.ArrivalTime < b.ArrivalTime
+	}
+	if a.Priority != b.Priority {
+		return morePriority(a.Priority, b.Priority)
+	}
+
+	return a.ProcessID < b.ProcessID
+}
+
+// byArrival reports whether process a should be queued before process b by the schedulers that serve processes
+// in the order they arrive: earlier arrival first, then TieBreak.
+func byArrival(a, b Process) bool {
+	if a.ArrivalTime != b.ArrivalTime {
+		return a.ArrivalTime < b.ArrivalTime
+	}
+
+	return TieBreak(a, b)
+}
+
+// lowerPID orders processes by lower PID alone.
+func lowerPID(a, b Process) bool {
+	return a.ProcessID < b.ProcessID
+}
+
+// HigherNumberIsHigherPriority selects the priority convention: when false, as by default,
+// a lower Priority number is a higher priority, and when true a higher Priority number is.
+var HigherNumberIsHigherPriority bool
+
+// morePriority reports whether priority a is a higher priority than b under HigherNumberIsHigherPriority.
+func morePriority(a, b int64) bool {
+	if HigherNumberIsHigherPriority {
+		return a > b
+	}
+
+	return a < b
+}
+
+// higherPriority reports whether a should be run before b: higher priority first, then TieBreak.
+func higherPriority(a, b Process) bool {
+	if a.Priority != b.Priority {
+		return morePriority(a.Priority, b.Priority)
+	}
+
+	return TieBreak(a, b)
+}
+
+// shorterJob reports whether process a with burst aBurst should be run before process b with burst bBurst
+// when the shortest job goes first: shorter burst first, then TieBreak.
+func shorterJob(a Process, aBurst int64, b Process, bBurst int64) bool {
+	if aBurst != bBurst {
+		return aBurst < bBurst
+	}
+
+	return TieBreak(a, b)
+}
+
+// longerJob reports whether process a with burst aBurst should be run before process b with burst bBurst
+// when the longest job goes first: longer burst first, then TieBreak.
+func longerJob(a Process, aBurst int64, b Process, bBurst int64) bool {
+	if aBurst != bBurst {
+		return aBurst > bBurst
+	}
+
+	return TieBreak(a, b)
+}
+
+// appendGantt records pid running from start until stop,
+// growing the last slice when it is the same pid and contiguous, otherwise starting a new slice.
+func appendGantt(gantt []TimeSlice, pid, start, stop int64) []TimeSlice {
+	if last := len(gantt) - 1; last >= 0 && gantt[last].PID == pid && gantt[last].Stop == start {
+		gantt[last].Stop = stop
+		return gantt
+	}
+
+	return append(gantt, TimeSlice{
+		PID:   pid,
+		Start: start,
+		Stop:  stop,
+	})
+}
+
+// contextSwitch records an overhead slice of cost time units when pid is dispatched straight after a different process,
+// returning the Gantt chart and the time pid can start running.
+// Dispatching from an idle CPU is free.
+func contextSwitch(gantt []TimeSlice, pid, currentTime, cost int64) ([]TimeSlice, int64) {
+	last := len(gantt) - 1
+	if cost == 0 || last < 0 || gantt[last].PID == pid || gantt[last].PID == IdlePID || gantt[last].PID == OverheadPID {
+		return gantt, currentTime
+	}
+
+	return append(gantt, TimeSlice{
+		PID:   OverheadPID,
+		Start: currentTime,
+		Stop:  currentTime + cost,
+	}), currentTime + cost
+}
+
+// agedPriority returns p with its Priority improved by one for every agingInterval time units it has waited.
+// An agingInterval of 0 disables aging.
+func agedPriority(p Process, waited, agingInterval int64) Process {
+	if agingInterval > 0 && HigherNumberIsHigherPriority {
+		p.Priority += waited / agingInterval
+	} else if agingInterval > 0 {
+		p.Priority -= waited / agingInterval
+	}
+
+	return p
+}
+
+// nextArrival returns the earliest arrival time of the processes that are not yet done.
+func nextArrival(processes []Process, done []bool) int64 {
+	next := int64(-1)
+	for i := range processes {
+		if !done[i] && (next == -1 || processes[i].ArrivalTime < next) {
+			next = processes[i].ArrivalTime
+		}
+	}
+
+	return next
+}
+
+// processStats computes the timing of each process from its completion time and its first slice in the Gantt chart.
+func processStats(processes []Process, completion []int64, gantt []TimeSlice) []ProcessStats {
+	firstStart := make(map[int64]int64, len(processes))
+	for i := len(gantt) - 1; i >= 0; i-- {
+		firstStart[gantt[i].PID] = gantt[i].Start
+	}
+	stats := make([]ProcessStats, len(processes))
+	for i := range processes {
+		turnaround := completion[i] - processes[i].ArrivalTime
+		stats[i] = ProcessStats{
+			Process:    processes[i],
+			Wait:       turnaround - processes[i].BurstDuration,
+			Turnaround: turnaround,
+			Response:   firstStart[processes[i].ProcessID] - processes[i].ArrivalTime,
+			Exit:       completion[i],
+		}
+	}
+
+	return stats
+}
+
+// newScheduleResult builds the table rows and averages for the per process stats of a schedule.
+func newScheduleResult(title string, stats []ProcessStats, gantt []TimeSlice) ScheduleResult {
+	var (
+		totalWait       float64
+		totalTurnaround float64
+		totalResponse   float64
+		totalNormalized float64
+		lastCompletion  float64
+		firstArrival    int64
+		rows            = make([][]string, len(stats))
+	)
+	for i, st := range stats {
+		if i == 0 || st.ArrivalTime < firstArrival {
+			firstArrival = st.ArrivalTime
+		}
+		totalWait += float64(st.Wait)
+		totalTurnaround += float64(st.Turnaround)
+		totalResponse += float64(st.Response)
+		totalNormalized += normalizedTurnaround(st)
+		if float64(st.Exit) > lastCompletion {
+			lastCompletion = float64(st.Exit)
+		}
+
+		rows[i] = []string{
+			fmt.Sprint(st.ProcessID),
+			fmt.Sprint(st.Priority),
+			formatTime(st.BurstDuration),
+			formatTime(st.ArrivalTime),
+			formatTime(st.Wait),
+			formatTime(st.Turnaround),
+			fmt.Sprintf("%.2f", normalizedTurnaround(st)),
+			formatTime(st.Response),
+			formatTime(st.Exit),
+		}
+	}
+
+	if len(stats) == 0 {
+		// leave the metrics at zero rather than dividing by no processes.
+		return ScheduleResult{Title: title, Gantt: gantt, Rows: rows, Stats: stats}
+	}
+
+	// the averages and throughput are in time units rather than ticks.
+	count := float64(len(stats))
+	scale := float64(timeScale())
+
+	return ScheduleResult{
+		Title:         title,
+		Gantt:         gantt,
+		Rows:          rows,
+		Stats:         stats,
+		AvgWait:       totalWait / count / scale,
+		AvgTurnaround: totalTurnaround / count / scale,
+		AvgResponse:   totalResponse / count / scale,
+		// normalized turnarounds are ratios, so they are not scaled.
+		AvgNormalizedTurnaround: totalNormalized / count,
+		Throughput:              count / ((lastCompletion - float64(firstArrival)) / scale),
+		Utilization:             cpuUtilization(gantt, firstArrival, int64(lastCompletion)),
+		Makespan:                int64(lastCompletion) - firstArrival,
+		Idle:                    idleTime(gantt, firstArrival, int64(lastCompletion)),
+	}
+}
+
+// normalizedTurnaround is the turnaround of st divided by its burst duration, or 0 for a process with no burst,
+// which validateProcesses rejects.
+func normalizedTurnaround(st ProcessStats) float64 {
+	if st.BurstDuration == 0 {
+		return 0
+	}
+	return float64(st.Turnaround) / float64(st.BurstDuration)
+}
+
+// idleTime is the total length of the idle slices between start and stop, summed across cores.
+// Idling before start, such as waiting for the first arrival, is not counted.
+func idleTime(gantt []TimeSlice, start, stop int64) int64 {
+	var idle int64
+	for _, slice := range gantt {
+		if slice.PID != IdlePID {
+			continue
+		}
+		from, to := slice.Start, slice.Stop
+		if from < start {
+			from = start
+		}
+		if to > stop {
+			to = stop
+		}
+		if to > from {
+			idle += to - from
+		}
+	}
+	return idle
+}
+
+// cpuUtilization is the fraction of the time between start and stop, the makespan, that the CPUs spent running processes,
+// so both idle time and context switch overhead count against it.
+// Idling before start, such as waiting for the first arrival, is not counted, as in idleTime.
+func cpuUtilization(gantt []TimeSlice, start, stop int64) float64 {
+	elapsed := stop - start
+	if elapsed <= 0 {
+		return 0
+	}
+	var (
+		busy  int64
+		cores = 1
+	)
+	for _, slice := range gantt {
+		if slice.Core > cores {
+			cores = slice.Core
+		}
+		if slice.PID == IdlePID || slice.PID == OverheadPID {
+			continue
+		}
+		from, to := slice.Start, slice.Stop
+		if from < start {
+			from = start
+		}
+		if to > stop {
+			to = stop
+		}
+		if to > from {
+			busy += to - from
+		}
+	}
+
+	return float64(busy) / float64(elapsed*int64(cores))
+}
+
+// defaultMLFQQuanta is the time quantum of each multilevel feedback queue level used when none are given on the command line.
+const defaultMLFQQuanta = "2,4,8"
+
+// MLFQSchedule outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes
+// • the time quantum of each level, highest level first, which must all be positive
+// • an aging interval, or 0 to disable aging
+// • a context switch cost, or 0 to switch for free
+func MLFQSchedule(w io.Writer, title string, processes []Process, quanta []int64, agingInterval, contextSwitchCost int64) {
+	Render(w, MLFQ(title, processes, quanta, agingInterval, contextSwitchCost))
+}
+
+// MLFQ schedules processes with a multilevel feedback queue.
+// Arrivals enter the highest level, and the highest non-empty level is always run first, round-robin within the level.
+// A process that uses its level's full quantum is demoted a level, and a process that has waited
+// agingInterval time units since it last ran is promoted a level so it cannot starve.
+// The running process is only reconsidered when its quantum is spent, it completes, or a process arrives or is promoted.
+func MLFQ(title string, processes []Process, quanta []int64, agingInterval, contextSwitchCost int64) ScheduleResult {
+	// sort a private copy so the caller's processes are left untouched.
+	local := append([]Process(nil), processes...)
+	sort.SliceStable(local, func(i, j int) bool {
+		return byArrival(local[i], local[j])
+	})
+
+	var (
+		currentTime     int64
+		completed       int
+		nextToAdmit     int
+		running         = -1
+		used            int64 // time the running process has used of its quantum.
+		queues          = make([][]int, len(quanta))
+		levels          = make([]int, len(local))
+		waitingSince    = make([]int64, len(local))
+		remainingBursts = make([]int64, len(local))
+		completion      = make([]int64, len(local))
+		gantt           = make([]TimeSlice, 0)
+	)
+	for i := range local {
+		remainingBursts[i] = local[i].BurstDuration
+	}
+	enqueue := func(i, level int) {
+		levels[i] = level
+		waitingSince[i] = currentTime
+		queues[level] = append(queues[level], i)
+	}
+	highestReady := func() int {
+		for level := range queues {
+			if len(queues[level]) > 0 {
+				return level
+			}
+		}
+		return -1
+	}
+
+	for completed < len(local) {
+		for nextToAdmit < len(local) && local[nextToAdmit].ArrivalTime <= currentTime {
+			enqueue(nextToAdmit, 0)
+			nextToAdmit++
+		}
+
+		// promote processes that have waited too long.
+		if agingInterval > 0 {
+			for level := 1; level < len(queues); level++ {
+				waiting := queues[level][:0]
+				for _, i := range queues[level] {
+					if currentTime-waitingSince[i] >= agingInterval {
+						enqueue(i, level-1)
+					} else {
+						waiting = append(waiting, i)
+					}
+				}
+				queues[level] = waiting
+			}
+		}
+
+		// the running process gives up the CPU when its quantum is spent or a higher level has work.
+		if running != -1 {
+			if ready := highestReady(); used == quanta[levels[running]] {
+				level := levels[running]
+				if level < len(queues)-1 {
+					level++
+				}
+				enqueue(running, level)
+				running = -1
+			} else if ready != -1 && ready < levels[running] {
+				enqueue(running, levels[running])
+				running = -1
+			}
+		}
+		if running == -1 {
+			level := highestReady()
+			if level == -1 {
+				// nothing is queued, so idle until the next arrival.
+				next := local[nextToAdmit].ArrivalTime
+				gantt = appendGantt(gantt, IdlePID, currentTime, next)
+				currentTime = next
+				continue
+			}
+			running = queues[level][0]
+			queues[level] = queues[level][1:]
+			used = 0
+			gantt, currentTime = contextSwitch(gantt, local[running].ProcessID, currentTime, contextSwitchCost)
+		}
+
+		stop := currentTime + quanta[levels[running]] - used
+		if end := currentTime + remainingBursts[running]; end < stop {
+			stop = end
+		}
+		if nextToAdmit < len(local) && local[nextToAdmit].ArrivalTime < stop {
+			stop = local[nextToAdmit].ArrivalTime
+		}
+		if agingInterval > 0 {
+			for level := 1; level < len(queues); level++ {
+				for _, i := range queues[level] {
+					if promoted := waitingSince[i] + agingInterval; promoted < stop {
+						stop = promoted
+					}
+				}
+			}
+		}
+		if stop <= currentTime {
+			// what happened during a context switch is only considered once the dispatched process has run for a tick.
+			stop = currentTime + 1
+		}
+
+		if last := len(gantt) - 1; last >= 0 && gantt[last].PID == local[running].ProcessID &&
+			gantt[last].Level == levels[running]+1 && gantt[last].Stop == currentTime {
+			gantt[last].Stop = stop
+		} else {
+			gantt = append(gantt, TimeSlice{
+				PID:   local[running].ProcessID,
+				Start: currentTime,
+				Stop:  stop,
+				Level: levels[running] + 1,
+			})
+		}
+		remainingBursts[running] -= stop - currentTime
+		used += stop - currentTime
+		currentTime = stop
+
+		if remainingBursts[running] == 0 {
+			completion[running] = currentTime
+			completed++
+			running = -1
+		}
+	}
+
+	return newScheduleResult(title, processStats(local, completion, gantt), gantt)
+}
+
+// LotterySchedule outputs a schedule of processes in a GANTT chart and a table of timing given:
+// • an output writer
+// • a title for the chart
+// • a slice of processes, whose priorities are their ticket counts
+// • a random seed, so the same seed draws the same winners
+// • a context switch cost, or 0 to switch for free
+// Each winner runs for the default quantum, in time units whatever the TimeDecimals.
+func LotterySchedule(w io.Writer, title string, processes []Process, seed, contextSwitchCost int64) {
+	Render(w, Lottery(title, processes, defaultQuantum*timeScale(), seed, contextSwitchCost))
+}
+
+// Lottery schedules processes by proportional share.
+// Every quantum a ticket is drawn from the arrived, unfinished processes, each holding as many tickets as its Priority
+// (at least one), and the winner runs for at most quantum time units, so processes get CPU time in proportion to their tickets.
+func Lottery(title string, processes []Process, quantum, seed, contextSwitchCost int64) ScheduleResult {
+	// sort a private copy so the caller's processes are left untouched.
+	local := append([]Process(nil), processes...)
+	sort.SliceStable(local, func(i, j int) bool {
+		return byArrival(local[i], local[j])
+	})
+
+	var (
+		rng             = rand.New(rand.NewSource(seed))
+		currentTime     int64
+		completed       int
+		remainingBursts = make([]int64, len(local))
+		completion      = make([]int64, len(local))
+		gantt           = make([]TimeSlice, 0)
+	)
+	for i := range local {
+		remainingBursts[i] = local[i].BurstDuration
+	}
+	tickets := func(p Process) int64 {
+		if p.Priority < 1 {
+			return 1
+		}
+		return p.Priority
+	}
+	for completed < len(local) {
+		var total int64
+		for i := range local {
+			if local[i].ArrivalTime <= currentTime && remainingBursts[i] > 0 {
+				total += tickets(local[i])
+			}
+		}
+		if total == 0 {
+			// everything that has arrived is done, so idle until the next arrival.
+			next := local[completed].ArrivalTime
+			gantt = append(gantt, TimeSlice{
+				PID:   IdlePID,
+				Start: currentTime,
+				Stop:  next,
+			})
+			currentTime = next
+			continue
+		}
+
+		winner := -1
+		for i, draw := 0, rng.Int63n(total); winner < 0; i++ {
+			if local[i].ArrivalTime > currentTime || remainingBursts[i] == 0 {
+				continue
+			}
+			if draw < tickets(local[i]) {
+				winner = i
+			}
+			draw -= tickets(local[i])
+		}
+
+		run := quantum
+		if remainingBursts[winner] < run {
+			run = remainingBursts[winner]
+		}
+		gantt, currentTime = contextSwitch(gantt, local[winner].ProcessID, currentTime, contextSwitchCost)
+		gantt = appendGantt(gantt, local[winner].ProcessID, currentTime, currentTime+run)
+		currentTime += run
+		remainingBursts[winner] -= run
+
+		if remainingBursts[winner] == 0 {
+			completion[winner] = currentTime
+			completed++
+		}
+	}
+
+	return newScheduleResult(title, processStats(local, completion, gantt), gantt)
+}
+
+//endregion
+
+//region Output helpers
+
+// Render prints the title, Gantt chart, and schedule table of a scheduler run.
+func Render(w io.Writer, r ScheduleResult) {
+	outputTitle(w, r.Title)
+	if len(r.Stats) == 0 {
+		_, _ = fmt.Fprint(w, noProcesses)
+		return
+	}
+	outputGantt(w, r.Gantt)
+	if VerboseGantt {
+		outputReadyQueues(w, r)
+	}
+	outputSchedule(w, r.Rows, r.AvgWait, r.AvgTurnaround, r.AvgNormalizedTurnaround, r.AvgResponse, r.Throughput, r.Utilization)
+}
+
+type (
+	// jsonReport is the machine-readable form of a scheduler run written by outputJSON.
+	jsonReport struct {
+		Title                       string        `json:"title"`
+		Processes                   []jsonProcess `json:"processes"`
+		Gantt                       []jsonSlice   `json:"gantt"`
+		AverageWait                 float64       `json:"averageWait"`
+		AverageTurnaround           float64       `json:"averageTurnaround"`
+		AverageNormalizedTurnaround float64       `json:"averageNormalizedTurnaround"`
+		AverageResponse             float64       `json:"averageResponse"`
+		Throughput                  float64       `json:"throughput"`
+		CPUUtilization              float64       `json:"cpuUtilization"`
+		Makespan                    json.Number   `json:"makespan"`
+		Idle                        json.Number   `json:"idle"`
+	}
+	// times are written as numbers with TimeDecimals decimals, exactly as in the table.
+	jsonProcess struct {
+		ID         int64       `json:"id"`
+		Priority   int64       `json:"priority"`
+		Burst      json.Number `json:"burst"`
+		Arrival    json.Number `json:"arrival"`
+		Wait       json.Number `json:"wait"`
+		Turnaround json.Number `json:"turnaround"`
+		Response   json.Number `json:"response"`
+		Exit       json.Number `json:"exit"`
+	}
+	jsonSlice struct {
+		PID   int64       `json:"pid"`
+		Start json.Number `json:"start"`
+		Stop  json.Number `json:"stop"`
+		Level int         `json:"level,omitempty"`
+		Core  int         `json:"core,omitempty"`
+	}
+)
+
+// outputJSON writes the results of a scheduler run as a single line JSON object.
+func outputJSON(w io.Writer, r ScheduleResult) {
+	report := jsonReport{
+		Title:                       r.Title,
+		Processes:                   make([]jsonProcess, len(r.Stats)),
+		Gantt:                       make([]jsonSlice, len(r.Gantt)),
+		AverageWait:                 r.AvgWait,
+		AverageTurnaround:           r.AvgTurnaround,
+		AverageNormalizedTurnaround: r.AvgNormalizedTurnaround,
+		AverageResponse:             r.AvgResponse,
+		Throughput:                  r.Throughput,
+		CPUUtilization:              r.Utilization,
+		Makespan:                    json.Number(formatTime(r.Makespan)),
+		Idle:                        json.Number(formatTime(r.Idle)),
+	}
+	for i, st := range r.Stats {
+		report.Processes[i] = jsonProcess{
+			ID:         st.ProcessID,
+			Priority:   st.Priority,
+			Burst:      json.Number(formatTime(st.BurstDuration)),
+			Arrival:    json.Number(formatTime(st.ArrivalTime)),
+			Wait:       json.Number(formatTime(st.Wait)),
+			Turnaround: json.Number(formatTime(st.Turnaround)),
+			Response:   json.Number(formatTime(st.Response)),
+			Exit:       json.Number(formatTime(st.Exit)),
+		}
+	}
+	for i, slice := range r.Gantt {
+		report.Gantt[i] = jsonSlice{
+			PID:   slice.PID,
+			Start: json.Number(formatTime(slice.Start)),
+			Stop:  json.Number(formatTime(slice.Stop)),
+			Level: slice.Level,
+			Core:  slice.Core,
+		}
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// noProcesses is printed in place of the Gantt chart and schedule table when there was nothing to schedule.
+const noProcesses = "No processes to schedule.\n\n"
+
+func outputTitle(w io.Writer, title string) {
+	_, _ = fmt.Fprintln(w, strings.Repeat("-", len(title)*2))
+	_, _ = fmt.Fprintln(w, strings.Repeat(" ", len(title)/2), title)
+	_, _ = fmt.Fprintln(w, strings.Repeat("-", len(title)*2))
+}
+
+// outputGantt prints the slices as a row of labelled cells with the boundary times aligned under the cell separators,
+// with one row per core when the slices ran on several cores.
+func outputGantt(w io.Writer, gantt []TimeSlice) {
+	_, _ = fmt.Fprintln(w, "Gantt schedule")
+
+	var cores int
+	for i := range gantt {
+		if gantt[i].Core > cores {
+			cores = gantt[i].Core
+		}
+	}
+	if cores == 0 {
+		outputGanttRow(w, gantt)
+		return
+	}
+	for core := 1; core <= cores; core++ {
+		var row []TimeSlice
+		for i := range gantt {
+			if gantt[i].Core == core {
+				row = append(row, gantt[i])
+			}
+		}
+		_, _ = fmt.Fprintf(w, "Core %d\n", core)
+		outputGanttRow(w, row)
+	}
+}
+
+func outputGanttRow(w io.Writer, gantt []TimeSlice) {
+	gantt = mergeSlices(gantt)
+
+	var cells, times strings.Builder
+	cells.WriteString("|")
+	for i := range gantt {
+		label := ganttLabel(gantt[i])
+		start := formatTime(gantt[i].Start)
+
+		// wide enough for the label padded by a space and for the start time to fit before the next separator.
+		width := 7
+		if len(label)+2 > width {
+			width = len(label) + 2
+		}
+		if len(start)+1 > width {
+			width = len(start) + 1
+		}
+		left := (width - len(label)) / 2
+		cells.WriteString(strings.Repeat(" ", left) + colorLabel(gantt[i].PID, label) + strings.Repeat(" ", width-len(label)-left) + "|")
+		times.WriteString(start + strings.Repeat(" ", width+1-len(start)))
+	}
+	if len(gantt) > 0 {
+		times.WriteString(formatTime(gantt[len(gantt)-1].Stop))
+	}
+
+	_, _ = fmt.Fprintln(w, cells.String())
+	_, _ = fmt.Fprintf(w, "%s\n\n", times.String())
+}
+
+// mergeSlices returns the slices with each run of contiguous slices for the same PID, level, and core
+// coalesced into a single slice, leaving gantt itself unchanged.
+func mergeSlices(gantt []TimeSlice) []TimeSlice {
+	merged := make([]TimeSlice, 0, len(gantt))
+	for _, slice := range gantt {
+		if last := len(merged) - 1; last >= 0 && merged[last].PID == slice.PID && merged[last].Level == slice.Level &&
+			merged[last].Core == slice.Core && merged[last].Stop == slice.Start {
+			merged[last].Stop = slice.Stop
+			continue
+		}
+		merged = append(merged, slice)
+	}
+
+	return merged
+}
+
+// VerboseGantt follows each Gantt chart with the processes waiting in the ready queue as each of its slices started.
+var VerboseGantt bool
+
+// outputReadyQueues prints a line per merged Gantt slice with the PIDs that were waiting when it started.
+func outputReadyQueues(w io.Writer, r ScheduleResult) {
+	_, _ = fmt.Fprintln(w, "Ready queue")
+	for _, slice := range mergeSlices(r.Gantt) {
+		waiting := "none"
+		if pids := waitingAt(r.Stats, r.Gantt, slice.Start); len(pids) > 0 {
+			ids := make([]string, len(pids))
+			for i := range pids {
+				ids[i] = fmt.Sprint(pids[i])
+			}
+			waiting = strings.Join(ids, ", ")
+		}
+		_, _ = fmt.Fprintf(w, "%s-%s\t%s\twaiting: %s\n", formatTime(slice.Start), formatTime(slice.Stop), ganttLabel(slice), waiting)
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+// waitingAt returns, in PID order, the processes that had arrived by now and had not exited
+// but were not running on any core at that time.
+func waitingAt(stats []ProcessStats, gantt []TimeSlice, now int64) []int64 {
+	running := make(map[int64]bool)
+	for _, slice := range gantt {
+		if slice.PID >= 0 && slice.Start <= now && now < slice.Stop {
+			running[slice.PID] = true
+		}
+	}
+	var pids []int64
+	for _, st := range stats {
+		if st.ArrivalTime <= now && now < st.Exit && !running[st.ProcessID] {
+			pids = append(pids, st.ProcessID)
+		}
+	}
+	sort.SliceStable(pids, func(i, j int) bool { return pids[i] < pids[j] })
+
+	return pids
+}
+
+// ColorGantt colors each process's Gantt cells with an ANSI color picked by its PID,
+// so a process has the same color in every scheduler's chart.
+var ColorGantt bool
+
+// ganttColors are the ANSI foreground color codes cycled through by PID.
+var ganttColors = []string{"31", "32", "33", "34", "35", "36", "91", "92", "93", "94", "95", "96"}
+
+// colorLabel wraps the label of pid's Gantt cell in its color when ColorGantt is set.
+// Idle and context switch slices are left plain.
+func colorLabel(pid int64, label string) string {
+	if !ColorGantt || pid < 0 {
+		return label
+	}
+
+	return "\x1b[" + ganttColors[pid%int64(len(ganttColors))] + "m" + label + "\x1b[0m"
+}
+
+// isTTY reports whether f is a terminal rather than a file or pipe.
+func isTTY(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// ganttLabel is the text shown in a slice's Gantt cell.
+func ganttLabel(slice TimeSlice) string {
+	if slice.PID == IdlePID {
+		return "<idle>"
+	}
+	if slice.PID == OverheadPID {
+		return "<cs>"
+	}
+	if slice.Level > 0 {
+		return fmt.Sprintf("%d:L%d", slice.PID, slice.Level)
+	}
+
+	return fmt.Sprint(slice.PID)
+}
+
+// SummaryCompare prints one row per algorithm, in title order, with the best average wait,
+// average turnaround, and throughput marked with a *.
+func SummaryCompare(w io.Writer, results map[string]ScheduleResult) {
+	outputTitle(w, "Summary")
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(summaryHeader)
+	table.SetColumnAlignment([]int{
+		tablewriter.ALIGN_LEFT,
+		tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT,
+	})
+	table.AppendBulk(summaryRows(results))
+	table.Render()
+}
+
+// SummaryCompareMarkdown writes the SummaryCompare table as a GitHub-flavored Markdown table.
+func SummaryCompareMarkdown(w io.Writer, results map[string]ScheduleResult) {
+	_, _ = fmt.Fprint(w, "### Summary\n\n")
+	outputMarkdownTable(w, summaryHeader, summaryRows(results))
+}
+
+var summaryHeader = []string{"Algorithm", "Average wait", "Average turnaround", "Throughput", "Makespan", "Idle"}
+
+// summaryRows builds the SummaryCompare rows in title order.
+func summaryRows(results map[string]ScheduleResult) [][]string {
+	titles := make([]string, 0, len(results))
+	for title := range results {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+
+	var bestWait, bestTurnaround, bestThroughput float64
+	for i, title := range titles {
+		r := results[title]
+		if i == 0 || r.AvgWait < bestWait {
+			bestWait = r.AvgWait
+		}
+		if i == 0 || r.AvgTurnaround < bestTurnaround {
+			bestTurnaround = r.AvgTurnaround
+		}
+		if i == 0 || r.Throughput > bestThroughput {
+			bestThroughput = r.Throughput
+		}
+	}
+
+	// every algorithm matching the best value is marked, so ties are all highlighted.
+	mark := func(s string, best bool) string {
+		if best {
+			return s + " *"
+		}
+		return s
+	}
+
+	rows := make([][]string, len(titles))
+	for i, title := range titles {
+		r := results[title]
+		rows[i] = []string{
+			title,
+			mark(fmt.Sprintf("%.2f", r.AvgWait), r.AvgWait == bestWait),
+			mark(fmt.Sprintf("%.2f", r.AvgTurnaround), r.AvgTurnaround == bestTurnaround),
+			mark(fmt.Sprintf("%.2f/t", r.Throughput), r.Throughput == bestThroughput),
+			formatTime(r.Makespan),
+			formatTime(r.Idle),
+		}
+	}
+
+	return rows
+}
+
+// DetailedView prints one row per process, in PID order, with a column per scheduler
+// holding the process's wait, turnaround, response, and exit time under that scheduler.
+func DetailedView(w io.Writer, results []ScheduleResult) {
+	outputTitle(w, "Detailed")
+	_, _ = fmt.Fprintln(w, detailedLegend)
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader(detailedHeader(results))
+	table.AppendBulk(detailedRows(results))
+	table.Render()
+}
+
+// DetailedViewMarkdown writes the DetailedView table as a GitHub-flavored Markdown table.
+func DetailedViewMarkdown(w io.Writer, results []ScheduleResult) {
+	_, _ = fmt.Fprintf(w, "### Detailed\n\n%s\n\n", detailedLegend)
+	outputMarkdownTable(w, detailedHeader(results), detailedRows(results))
+}
+
+const detailedLegend = "Each cell is wait / turnaround / response / exit"
+
+func detailedHeader(results []ScheduleResult) []string {
+	header := []string{"ID"}
+	for _, r := range results {
+		header = append(header, r.Title)
+	}
+
+	return header
+}
+
+// detailedRows builds the DetailedView rows in PID order.
+func detailedRows(results []ScheduleResult) [][]string {
+	var pids []int64
+	byPID := make(map[int64][]string)
+	for i, r := range results {
+		for _, st := range r.Stats {
+			row, ok := byPID[st.ProcessID]
+			if !ok {
+				row = make([]string, 1+len(results))
+				row[0] = fmt.Sprint(st.ProcessID)
+				byPID[st.ProcessID] = row
+				pids = append(pids, st.ProcessID)
+			}
+			row[1+i] = strings.Join([]string{formatTime(st.Wait), formatTime(st.Turnaround), formatTime(st.Response), formatTime(st.Exit)}, " / ")
+		}
+	}
+	sort.SliceStable(pids, func(i, j int) bool { return pids[i] < pids[j] })
+
+	rows := make([][]string, len(pids))
+	for i, pid := range pids {
+		rows[i] = byPID[pid]
+	}
+
+	return rows
+}
+
+// starvedProcesses returns the processes that waited longer than threshold, in schedule table order.
+func starvedProcesses(r ScheduleResult, threshold int64) []ProcessStats {
+	var starved []ProcessStats
+	for _, st := range r.Stats {
+		if st.Wait > threshold {
+			starved = append(starved, st)
+		}
+	}
+
+	return starved
+}
+
+// outputStarvation prints a warning listing the processes that waited longer than threshold, if any did.
+func outputStarvation(w io.Writer, r ScheduleResult, threshold int64) {
+	starved := starvedProcesses(r, threshold)
+	if len(starved) == 0 {
+		return
+	}
+	waits := make([]string, len(starved))
+	for i, st := range starved {
+		waits[i] = fmt.Sprintf("PID %d waited %s", st.ProcessID, formatTime(st.Wait))
+	}
+	_, _ = fmt.Fprintf(w, "Warning: %s starved processes waiting longer than %s: %s\n\n",
+		r.Title, formatTime(threshold), strings.Join(waits, ", "))
+}
+
+// scheduleHeader is the header of the schedule table, matching the columns of ScheduleResult.Rows.
+var scheduleHeader = []string{"ID", "Priority", "Burst", "Arrival", "Wait", "Turnaround", "NTaT", "Response", "Exit"}
+
+// scheduleFooter is the averages row of the schedule table, each cell a label and a value on separate lines.
+func scheduleFooter(wait, turnaround, normalized, response, throughput, utilization float64) []string {
+	return []string{"", "", "",
+		fmt.Sprintf("Utilization\n%.2f%%", utilization*100),
+		fmt.Sprintf("Average\n%.2f", wait),
+		fmt.Sprintf("Average\n%.2f", turnaround),
+		fmt.Sprintf("Average\n%.2f", normalized),
+		fmt.Sprintf("Average\n%.2f", response),
+		fmt.Sprintf("Throughput\n%.2f/t", throughput)}
+}
+
+func outputSchedule(w io.Writer, rows [][]string, wait, turnaround, normalized, response, throughput, utilization float64) {
+	_, _ = fmt.Fprintln(w, "Schedule table")
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(scheduleHeader)
+	table.AppendBulk(rows)
+	table.SetFooter(scheduleFooter(wait, turnaround, normalized, response, throughput, utilization))
+	table.Render()
+}
+
+// outputMarkdown writes a scheduler run as GitHub-flavored Markdown: a heading,
+// the Gantt chart in a fenced code block, and the schedule table with the averages as its last row.
+func outputMarkdown(w io.Writer, r ScheduleResult) {
+	var gantt strings.Builder
+	outputGantt(&gantt, r.Gantt)
+
+	_, _ = fmt.Fprintf(w, "### %s\n\n", r.Title)
+	if len(r.Stats) == 0 {
+		_, _ = fmt.Fprint(w, noProcesses)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "```text\n%s\n```\n\n", strings.TrimRight(gantt.String(), "\n"))
+	footer := scheduleFooter(r.AvgWait, r.AvgTurnaround, r.AvgNormalizedTurnaround, r.AvgResponse, r.Throughput, r.Utilization)
+	for i := range footer {
+		footer[i] = strings.ReplaceAll(footer[i], "\n", " ")
+	}
+	rows := append(append([][]string(nil), r.Rows...), footer)
+	outputMarkdownTable(w, scheduleHeader, rows)
+}
+
+// outputCSV writes a scheduler run as CSV for other tools to analyze, in sections separated by a blank line.
+// The first section is the title on its own, then the schedule table with its header row and a last row of the averages
+// along with the throughput and CPU utilization, and the last is the Gantt chart as PID,Start,Stop rows, where an idle CPU is PID -1.
+func outputCSV(w io.Writer, r ScheduleResult) {
+	cw := csv.NewWriter(w)
+	section := func(records ...[]string) {
+		_ = cw.WriteAll(records)
+		_, _ = fmt.Fprintln(w)
+	}
+
+	section([]string{r.Title})
+	if len(r.Stats) == 0 {
+		return
+	}
+	// the run's throughput and CPU utilization take two more columns, left empty but for the averages row.
+	schedule := [][]string{append(append([]string(nil), scheduleHeader...), "Throughput", "Utilization %")}
+	for _, row := range r.Rows {
+		schedule = append(schedule, append(append([]string(nil), row...), "", ""))
+	}
+	schedule = append(schedule, []string{"Average", "", "", "",
+		fmt.Sprintf("%.2f", r.AvgWait),
+		fmt.Sprintf("%.2f", r.AvgTurnaround),
+		fmt.Sprintf("%.2f", r.AvgNormalizedTurnaround),
+		fmt.Sprintf("%.2f", r.AvgResponse),
+		"",
+		fmt.Sprintf("%.2f", r.Throughput),
+		fmt.Sprintf("%.2f", r.Utilization*100)})
+	section(schedule...)
+
+	gantt := [][]string{{"PID", "Start", "Stop"}}
+	for _, slice := range r.Gantt {
+		gantt = append(gantt, []string{fmt.Sprint(slice.PID), formatTime(slice.Start), formatTime(slice.Stop)})
+	}
+	section(gantt...)
+}
+
+// outputMarkdownTable writes a Markdown table followed by a blank line, escaping any pipes in the cells.
+func outputMarkdownTable(w io.Writer, header []string, rows [][]string) {
+	line := func(cells []string) {
+		escaped := make([]string, len(cells))
+		for i := range cells {
+			escaped[i] = strings.ReplaceAll(cells[i], "|", "\\|")
+		}
+		_, _ = fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+	}
+
+	line(header)
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	line(separator)
+	for _, row := range rows {
+		line(row)
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+//endregion
+
+//region Loading processes.
+
+var (
+	ErrInvalidArgs = errors.New("invalid args")
+	ErrInvalidRow  = errors.New("invalid row")
+)
+
+// headerNames are the accepted header names for each Process field, in the default column order.
+var headerNames = [...][]string{
+	{"ProcessID", "PID", "ID"},
+	{"BurstDuration", "Burst"},
+	{"ArrivalTime", "Arrival"},
+	{"Priority"},
+}
+
+// loadProcesses reads processes from CSV rows of ProcessID,BurstDuration,ArrivalTime[,Priority],
+// where the burst duration and arrival time may have up to TimeDecimals decimals.
+// A non-numeric first row is treated as a header, and columns are then mapped by name so they can be in any order.
+func loadProcesses(r io.Reader) ([]Process, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // rows are checked individually below.
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading CSV", err)
+	}
+
+	var (
+		columns    = [len(headerNames)]int{0, 1, 2, 3}
+		minColumns = 3
+		first      = true
+		processes  = make([]Process, 0, len(rows))
+	)
+	for i := range rows {
+		if isEmptyRow(rows[i]) {
+			continue
+		}
+		if first {
+			first = false
+			if _, err := strToInt(rows[i][0]); err != nil {
+				if columns, err = headerColumns(rows[i]); err != nil {
+					return nil, fmt.Errorf("row %d: %w", i+1, err)
+				}
+				minColumns = 0
+				for _, col := range columns[:3] {
+					if col+1 > minColumns {
+						minColumns = col + 1
+					}
+				}
+				continue
+			}
+		}
+		if len(rows[i]) < minColumns {
+			return nil, fmt.Errorf("%w: row %d: expected at least %d columns, got %d", ErrInvalidRow, i+1, minColumns, len(rows[i]))
+		}
+
+		var p Process
+		fields := [len(headerNames)]*int64{&p.ProcessID, &p.BurstDuration, &p.ArrivalTime, &p.Priority}
+		for f, col := range columns {
+			if col < 0 || col >= len(rows[i]) {
+				continue // priority is optional.
+			}
+			if f == 1 || f == 2 {
+				if *fields[f], err = parseTime(rows[i][col], TimeDecimals); err != nil {
+					return nil, fmt.Errorf("row %d column %d: %q is not a time: %w", i+1, col+1, rows[i][col], err)
+				}
+			} else if *fields[f], err = strToInt(rows[i][col]); err != nil {
+				return nil, fmt.Errorf("row %d column %d: %q is not an integer: %w", i+1, col+1, rows[i][col], err)
+			}
+		}
+		processes = append(processes, p)
+	}
+
+	return processes, nil
+}
+
+// outputCheck prints a summary of valid processes: how many there are, their total burst, and when they arrive.
+func outputCheck(w io.Writer, processes []Process) {
+	if len(processes) == 0 {
+		_, _ = fmt.Fprintln(w, "0 processes")
+		return
+	}
+	var (
+		totalBurst   int64
+		firstArrival = processes[0].ArrivalTime
+		lastArrival  = processes[0].ArrivalTime
+	)
+	for _, p := range processes {
+		totalBurst += p.BurstDuration
+		if p.ArrivalTime < firstArrival {
+			firstArrival = p.ArrivalTime
+		}
+		if p.ArrivalTime > lastArrival {
+			lastArrival = p.ArrivalTime
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%d processes, total burst %s, arriving from %s to %s\n",
+		len(processes), formatTime(totalBurst), formatTime(firstArrival), formatTime(lastArrival))
+}
+
+// validateProcesses rejects processes the schedulers cannot run: a burst that is not positive,
+// a negative arrival time, or a process ID used more than once.
+// Rows are counted from 1 in the order the processes were loaded.
+func validateProcesses(processes []Process) error {
+	seen := make(map[int64]int, len(processes))
+	for i, p := range processes {
+		if p.BurstDuration <= 0 {
+			return fmt.Errorf("%w: row %d: burst duration must be positive, got %s", ErrInvalidRow, i+1, formatTime(p.BurstDuration))
+		}
+		if p.ArrivalTime < 0 {
+			return fmt.Errorf("%w: row %d: arrival time must not be negative, got %s", ErrInvalidRow, i+1, formatTime(p.ArrivalTime))
+		}
+		if row, ok := seen[p.ProcessID]; ok {
+			return fmt.Errorf("%w: row %d: process ID %d already used in row %d", ErrInvalidRow, i+1, p.ProcessID, row)
+		}
+		seen[p.ProcessID] = i + 1
+	}
+
+	return nil
+}
+
+// headerColumns maps a header row to the column index of each Process field, in headerNames order.
+// The Priority column is optional and is -1 when missing.
+func headerColumns(header []string) ([len(headerNames)]int, error) {
+	columns := [len(headerNames)]int{-1, -1, -1, -1}
+	for col, name := range header {
+		name = strings.NewReplacer(" ", "", "_", "").Replace(strings.TrimSpace(name))
+		for f := range headerNames {
+			for _, want := range headerNames[f] {
+				if strings.EqualFold(name, want) {
+					columns[f] = col
+				}
+			}
+		}
+	}
+	for f, col := range columns[:3] {
+		if col == -1 {
+			return columns, fmt.Errorf("%w: header has no %s column", ErrInvalidRow, headerNames[f][0])
+		}
+	}
+
+	return columns, nil
+}
+
+// isEmptyRow reports whether every field in the row is blank.
+func isEmptyRow(row []string) bool {
+	for _, field := range row {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GenerateProcesses returns n random processes, numbered from 1 in order of arrival, with bursts in [1, maxBurst],
+// arrivals in [0, maxArrival], and priorities in [1, maxPriority].
+// The same seed always generates the same processes.
+func GenerateProcesses(n int, seed int64, maxBurst, maxArrival, maxPriority int64) []Process {
+	rng := rand.New(rand.NewSource(seed))
+	processes := make([]Process, n)
+	for i := range processes {
+		processes[i] = Process{
+			BurstDuration: rng.Int63n(maxBurst) + 1,
+			ArrivalTime:   rng.Int63n(maxArrival + 1),
+			Priority:      rng.Int63n(maxPriority) + 1,
+		}
+	}
+	sort.SliceStable(processes, func(i, j int) bool {
+		return processes[i].ArrivalTime < processes[j].ArrivalTime
+	})
+	for i := range processes {
+		processes[i].ProcessID = int64(i + 1)
+	}
+
+	return processes
+}
+
+// writeProcesses writes processes as CSV with a header row, in the format loadProcesses reads.
+func writeProcesses(w io.Writer, processes []Process) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"ProcessID", "BurstDuration", "ArrivalTime", "Priority"})
+	for _, p := range processes {
+		_ = cw.Write([]string{
+			fmt.Sprint(p.ProcessID),
+			formatTime(p.BurstDuration),
+			formatTime(p.ArrivalTime),
+			fmt.Sprint(p.Priority),
+		})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// TimeDecimals is the number of decimals times are given and shown with.
+// Times are held as int64 counts of ticks, 10^-TimeDecimals time units each,
+// so the schedulers work in whole ticks whatever the precision.
+var TimeDecimals int
+
+// maxDecimals keeps a time unit's worth of ticks well within int64.
+const maxDecimals = 9
+
+// timeScale is the number of ticks in a time unit.
+func timeScale() int64 {
+	scale := int64(1)
+	for i := 0; i < TimeDecimals; i++ {
+		scale *= 10
+	}
+
+	return scale
+}
+
+// parseTime parses a base 10 time with up to decimals decimals, ignoring surrounding whitespace,
+// into a count of 10^-decimals ticks.
+func parseTime(s string, decimals int) (int64, error) {
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) > decimals || strings.TrimLeft(whole+frac, "+-") == "" {
+		return 0, &strconv.NumError{Func: "parseTime", Num: s, Err: strconv.ErrSyntax}
+	}
+	ticks, err := strconv.ParseInt(whole+frac+strings.Repeat("0", decimals-len(frac)), 10, 64)
+	if err != nil {
+		return 0, &strconv.NumError{Func: "parseTime", Num: s, Err: err.(*strconv.NumError).Err}
+	}
+
+	return ticks, nil
+}
+
+// formatTime formats a count of ticks as a time with TimeDecimals decimals.
+func formatTime(ticks int64) string {
+	if TimeDecimals == 0 {
+		return strconv.FormatInt(ticks, 10)
+	}
+	sign := ""
+	if ticks < 0 {
+		sign, ticks = "-", -ticks
+	}
+	scale := timeScale()
+
+	return fmt.Sprintf("%s%d.%0*d", sign, ticks/scale, TimeDecimals, ticks%scale)
+}
+
+// strToInt parses a base 10 integer, ignoring surrounding whitespace.
+func strToInt(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
 }
 
 //endregion
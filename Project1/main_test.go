@@ -2,11 +2,16 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"os"
 	"path"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"testing/iotest"
@@ -50,82 +55,2752 @@ func TestFCFSSchedule(t *testing.T) {
 			},
 			wantOut: loadFixture(t, "fcfs_test.txt"),
 		},
+		{
+			name: "idle gap before a late arrival",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 5,
+						Priority:      1,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   10,
+						BurstDuration: 3,
+						Priority:      1,
+					},
+				},
+				title: "First-come, first-serve",
+			},
+			wantOut: loadFixture(t, "fcfs_idle_test.txt"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			FCFSSchedule(&w, tt.args.title, tt.args.processes, 0)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("FCFSSchedule() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestFCFSMulti(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 4},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 4},
+		{ProcessID: 3, ArrivalTime: 0, BurstDuration: 4},
+		{ProcessID: 4, ArrivalTime: 0, BurstDuration: 4},
+	}
+	// the first two jobs run together, then the last two once those cores free up.
+	wantGantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 4, Core: 1},
+		{PID: 2, Start: 0, Stop: 4, Core: 2},
+		{PID: 3, Start: 4, Stop: 8, Core: 1},
+		{PID: 4, Start: 4, Stop: 8, Core: 2},
+	}
+
+	got := FCFSMulti("First-come, first-serve", processes, 2, 0)
+	if !reflect.DeepEqual(got.Gantt, wantGantt) {
+		t.Errorf("FCFSMulti() gantt = %v, want %v", got.Gantt, wantGantt)
+	}
+	if want := 2.0; got.AvgWait != want {
+		t.Errorf("FCFSMulti() wait = %v, want %v", got.AvgWait, want)
+	}
+	if want := 6.0; got.AvgTurnaround != want {
+		t.Errorf("FCFSMulti() turnaround = %v, want %v", got.AvgTurnaround, want)
+	}
+	if want := 0.5; got.Throughput != want {
+		t.Errorf("FCFSMulti() throughput = %v, want %v", got.Throughput, want)
+	}
+	if want := 1.0; got.Utilization != want {
+		t.Errorf("FCFSMulti() utilization = %v, want %v", got.Utilization, want)
+	}
+}
+
+func TestSJFSchedule(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		processes []Process
+		title     string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut string
+	}{
+		{
+			name: "staggered arrivals run to completion",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 8,
+						Priority:      2,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   1,
+						BurstDuration: 4,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   2,
+						BurstDuration: 2,
+						Priority:      3,
+					},
+				},
+				title: "Shortest-job-first",
+			},
+			wantOut: loadFixture(t, "sjf_test.txt"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			SJFSchedule(&w, tt.args.title, tt.args.processes, 0)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("SJFSchedule() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestSRTFSchedule(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		processes []Process
+		title     string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut string
+	}{
+		{
+			name: "staggered arrivals preempt the running job",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 8,
+						Priority:      2,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   1,
+						BurstDuration: 4,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   2,
+						BurstDuration: 2,
+						Priority:      3,
+					},
+				},
+				title: "Shortest-remaining-time-first",
+			},
+			wantOut: loadFixture(t, "srtf_test.txt"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			SRTFSchedule(&w, tt.args.title, tt.args.processes, 0)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("SRTFSchedule() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+// srtfPerTick is the original SRTF, which rescans every process each time unit.
+// It is kept as a reference for the event-driven SRTF.
+func srtfPerTick(title string, processes []Process, contextSwitchCost int64) ScheduleResult {
+	var (
+		currentTime     int64
+		completed       int
+		remainingBursts = make([]int64, len(processes))
+		completion      = make([]int64, len(processes))
+		gantt           = make([]TimeSlice, 0)
+	)
+	for i := range processes {
+		remainingBursts[i] = processes[i].BurstDuration
+	}
+	for completed < len(processes) {
+		shortest := -1
+		for i := range processes {
+			if remainingBursts[i] == 0 || processes[i].ArrivalTime > currentTime {
+				continue
+			}
+			if shortest == -1 || shorterJob(processes[i], remainingBursts[i], processes[shortest], remainingBursts[shortest]) {
+				shortest = i
+			}
+		}
+		if shortest == -1 {
+			gantt = appendGantt(gantt, IdlePID, currentTime, currentTime+1)
+			currentTime++
+			continue
+		}
+
+		gantt, currentTime = contextSwitch(gantt, processes[shortest].ProcessID, currentTime, contextSwitchCost)
+		gantt = appendGantt(gantt, processes[shortest].ProcessID, currentTime, currentTime+1)
+		remainingBursts[shortest]--
+		currentTime++
+
+		if remainingBursts[shortest] == 0 {
+			completion[shortest] = currentTime
+			completed++
+		}
+	}
+
+	return newScheduleResult(title, processStats(processes, completion, gantt), gantt)
+}
+
+func TestSRTFMatchesPerTick(t *testing.T) {
+	t.Parallel()
+	for seed := int64(1); seed <= 20; seed++ {
+		// a busy workload with frequent preemption, then a sparse one with idle gaps.
+		for _, processes := range [][]Process{
+			GenerateProcesses(30, seed, 10, 40, 5),
+			GenerateProcesses(10, seed, 3, 100, 5),
+		} {
+			for _, cost := range []int64{0, 1, 3} {
+				want := srtfPerTick("SRTF", processes, cost)
+				got := SRTF("SRTF", processes, cost)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("SRTF() seed %d switch cost %d = %+v, want %+v", seed, cost, got, want)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkSRTF(b *testing.B) {
+	processes := GenerateProcesses(500, 1, 1000, 10000, 5)
+	b.Run("event-driven", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			SRTF("SRTF", processes, 0)
+		}
+	})
+	b.Run("per-tick", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			srtfPerTick("SRTF", processes, 0)
+		}
+	})
+}
+
+func TestLJFSchedule(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		processes []Process
+		title     string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut string
+	}{
+		{
+			name: "longest available job runs to completion",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 2,
+						Priority:      1,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   1,
+						BurstDuration: 6,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   2,
+						BurstDuration: 4,
+						Priority:      1,
+					},
+				},
+				title: "Longest-job-first",
+			},
+			wantOut: loadFixture(t, "ljf_test.txt"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			LJFSchedule(&w, tt.args.title, tt.args.processes, 0)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("LJFSchedule() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestLRTFSchedule(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		processes []Process
+		title     string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut string
+	}{
+		{
+			name: "longer arrival preempts the running job",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 2,
+						Priority:      1,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   1,
+						BurstDuration: 6,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   2,
+						BurstDuration: 4,
+						Priority:      1,
+					},
+				},
+				title: "Longest-remaining-time-first",
+			},
+			wantOut: loadFixture(t, "lrtf_test.txt"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			LRTFSchedule(&w, tt.args.title, tt.args.processes, 0)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("LRTFSchedule() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestPreemptivePrioritySchedule(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		processes     []Process
+		title         string
+		agingInterval int64
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut string
+	}{
+		{
+			name: "late high priority arrival preempts running job",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 6,
+						Priority:      3,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   2,
+						BurstDuration: 3,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   4,
+						BurstDuration: 2,
+						Priority:      2,
+					},
+				},
+				title: "Preemptive priority",
+			},
+			wantOut: loadFixture(t, "preemptive_priority_test.txt"),
+		},
+		{
+			name: "aging lets a starving job preempt",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 6,
+						Priority:      1,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   0,
+						BurstDuration: 2,
+						Priority:      3,
+					},
+				},
+				title:         "Preemptive priority",
+				agingInterval: 1,
+			},
+			wantOut: loadFixture(t, "preemptive_priority_aging_test.txt"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			PreemptivePrioritySchedule(&w, tt.args.title, tt.args.processes, tt.args.agingInterval, 0)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("PreemptivePrioritySchedule() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestSJFPrioritySchedule(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		processes     []Process
+		title         string
+		agingInterval int64
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut string
+	}{
+		{
+			name: "highest priority arrived job runs next",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 4,
+						Priority:      2,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   1,
+						BurstDuration: 3,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   1,
+						BurstDuration: 5,
+						Priority:      3,
+					},
+				},
+				title: "Priority",
+			},
+			wantOut: loadFixture(t, "priority_test.txt"),
+		},
+		{
+			name: "swapping only priorities changes the order",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 4,
+						Priority:      2,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   1,
+						BurstDuration: 3,
+						Priority:      3,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   1,
+						BurstDuration: 5,
+						Priority:      1,
+					},
+				},
+				title: "Priority",
+			},
+			wantOut: loadFixture(t, "priority_swapped_test.txt"),
+		},
+		{
+			name: "equal priorities go to the shorter burst",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 4,
+						Priority:      1,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   1,
+						BurstDuration: 6,
+						Priority:      2,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   1,
+						BurstDuration: 2,
+						Priority:      2,
+					},
+				},
+				title: "Priority",
+			},
+			wantOut: loadFixture(t, "priority_tie_test.txt"),
+		},
+		{
+			name: "aging lets a starving job run",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 3,
+						Priority:      1,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   0,
+						BurstDuration: 3,
+						Priority:      5,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   2,
+						BurstDuration: 3,
+						Priority:      1,
+					},
+					{
+						ProcessID:     4,
+						ArrivalTime:   5,
+						BurstDuration: 3,
+						Priority:      1,
+					},
+					{
+						ProcessID:     5,
+						ArrivalTime:   8,
+						BurstDuration: 3,
+						Priority:      1,
+					},
+				},
+				title:         "Priority",
+				agingInterval: 1,
+			},
+			wantOut: loadFixture(t, "priority_aging_test.txt"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			SJFPrioritySchedule(&w, tt.args.title, tt.args.processes, tt.args.agingInterval, 0)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("SJFPrioritySchedule() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestRRSchedule(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		processes []Process
+		title     string
+		quantum   int64
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut string
+	}{
+		{
+			name: "default",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 5,
+						Priority:      2,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   3,
+						BurstDuration: 9,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   6,
+						BurstDuration: 6,
+						Priority:      3,
+					},
+				},
+				title:   "Round-robin",
+				quantum: 4,
+			},
+			wantOut: loadFixture(t, "rr_test.txt"),
+		},
+		{
+			name: "quantum of one",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 5,
+						Priority:      2,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   3,
+						BurstDuration: 9,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   6,
+						BurstDuration: 6,
+						Priority:      3,
+					},
+				},
+				title:   "Round-robin",
+				quantum: 1,
+			},
+			wantOut: loadFixture(t, "rr_quantum1_test.txt"),
+		},
+		{
+			name: "quantum longer than every burst is FCFS",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 5,
+						Priority:      2,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   3,
+						BurstDuration: 9,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   6,
+						BurstDuration: 6,
+						Priority:      3,
+					},
+				},
+				title:   "First-come, First-serve",
+				quantum: 10,
+			},
+			wantOut: loadFixture(t, "fcfs_test.txt"),
+		},
+		{
+			name: "jobs finishing before later arrivals are all admitted",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 2,
+						Priority:      1,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   2,
+						BurstDuration: 3,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   8,
+						BurstDuration: 2,
+						Priority:      1,
+					},
+				},
+				title:   "Round-robin",
+				quantum: 4,
+			},
+			wantOut: loadFixture(t, "rr_arrivals_test.txt"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			RRSchedule(&w, tt.args.title, tt.args.processes, tt.args.quantum, 0)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("RRSchedule() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestRRContextSwitchCost(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 6, BurstDuration: 6, Priority: 3},
+	}
+
+	free := RR("Round-robin", processes, 2, 0)
+	for _, slice := range free.Gantt {
+		if slice.PID == OverheadPID {
+			t.Fatalf("RR() with no switch cost recorded overhead slice %v", slice)
+		}
+	}
+
+	prev := free
+	for _, cost := range []int64{1, 2} {
+		got := RR("Round-robin", processes, 2, cost)
+		if got.AvgTurnaround <= prev.AvgTurnaround {
+			t.Errorf("RR() switch cost %d turnaround = %v, want more than %v", cost, got.AvgTurnaround, prev.AvgTurnaround)
+		}
+		if got.Throughput >= prev.Throughput {
+			t.Errorf("RR() switch cost %d throughput = %v, want less than %v", cost, got.Throughput, prev.Throughput)
+		}
+		for i, slice := range got.Gantt {
+			if slice.PID != OverheadPID {
+				continue
+			}
+			if slice.Stop-slice.Start != cost {
+				t.Errorf("RR() switch cost %d overhead slice = %v, want %d long", cost, slice, cost)
+			}
+			if i == 0 || i == len(got.Gantt)-1 || got.Gantt[i-1].PID == got.Gantt[i+1].PID {
+				t.Errorf("RR() switch cost %d overhead slice %v is not between two processes", cost, slice)
+			}
+		}
+		prev = got
+	}
+}
+
+func TestNonPreemptiveContextSwitchCost(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 6, BurstDuration: 6, Priority: 3},
+		{ProcessID: 4, ArrivalTime: 30, BurstDuration: 2, Priority: 1},
+	}
+	got := SJF("Shortest-job-first", processes, 2)
+	// process 4 starts from an idle CPU, so it is dispatched for free.
+	wantGantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 5},
+		{PID: OverheadPID, Start: 5, Stop: 7},
+		{PID: 2, Start: 7, Stop: 16},
+		{PID: OverheadPID, Start: 16, Stop: 18},
+		{PID: 3, Start: 18, Stop: 24},
+		{PID: IdlePID, Start: 24, Stop: 30},
+		{PID: 4, Start: 30, Stop: 32},
+	}
+	if !reflect.DeepEqual(got.Gantt, wantGantt) {
+		t.Errorf("SJF() gantt = %v, want %v", got.Gantt, wantGantt)
+	}
+	// the waits include the switches before each process runs.
+	wantWait := map[int64]int64{1: 0, 2: 4, 3: 12, 4: 0}
+	for _, stat := range got.Stats {
+		if stat.Wait != wantWait[stat.ProcessID] {
+			t.Errorf("SJF() PID %d wait = %d, want %d", stat.ProcessID, stat.Wait, wantWait[stat.ProcessID])
+		}
+	}
+
+	// each core pays for its own switches.
+	multi := FCFSMulti("First-come, first-serve", []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9},
+		{ProcessID: 3, ArrivalTime: 4, BurstDuration: 6},
+	}, 2, 2)
+	wantMulti := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 5, Core: 1},
+		{PID: IdlePID, Start: 0, Stop: 3, Core: 2},
+		{PID: 2, Start: 3, Stop: 12, Core: 2},
+		{PID: OverheadPID, Start: 5, Stop: 7, Core: 1},
+		{PID: 3, Start: 7, Stop: 13, Core: 1},
+	}
+	if !reflect.DeepEqual(multi.Gantt, wantMulti) {
+		t.Errorf("FCFSMulti() gantt = %v, want %v", multi.Gantt, wantMulti)
+	}
+}
+
+func TestRRWaitMatchesGantt(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 6, BurstDuration: 6, Priority: 3},
+	}
+	for _, cost := range []int64{0, 1} {
+		got := RR("Round-robin", processes, 2, cost)
+		for _, row := range got.Rows {
+			pid, arrival, wait := row[0], row[3], row[4]
+			var first, last, ran int64 = -1, 0, 0
+			for _, slice := range got.Gantt {
+				if strconv.FormatInt(slice.PID, 10) != pid {
+					continue
+				}
+				if first < 0 {
+					first = slice.Start
+				}
+				last = slice.Stop
+				ran += slice.Stop - slice.Start
+			}
+			// the time spent waiting is every tick between arrival and exit the process did not run.
+			arrived, err := strconv.ParseInt(arrival, 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strconv.FormatInt(last-arrived-ran, 10); wait != want {
+				t.Errorf("RR() switch cost %d PID %s wait = %s, want %s from the Gantt", cost, pid, wait, want)
+			}
+			if waited, _ := strconv.ParseInt(wait, 10, 64); first-arrived > waited {
+				t.Errorf("RR() switch cost %d PID %s first started at %d, after its %s wait", cost, pid, first, wait)
+			}
+		}
+	}
+}
+
+// TestTrace is not parallel because it sets Trace.
+// TestNewArrivalsFirst is not parallel because it replaces the package's round-robin queueing policy.
+func TestNewArrivalsFirst(t *testing.T) {
+	t.Cleanup(func() { NewArrivalsFirst = false })
+	// PID 2 arrives just as PID 1's first quantum ends.
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 4},
+		{ProcessID: 2, ArrivalTime: 2, BurstDuration: 2},
+	}
+	tests := []struct {
+		newArrivalsFirst bool
+		wantGantt        []TimeSlice
+	}{
+		{
+			newArrivalsFirst: false,
+			wantGantt: []TimeSlice{
+				{PID: 1, Start: 0, Stop: 2},
+				{PID: 1, Start: 2, Stop: 4},
+				{PID: 2, Start: 4, Stop: 6},
+			},
+		},
+		{
+			newArrivalsFirst: true,
+			wantGantt: []TimeSlice{
+				{PID: 1, Start: 0, Stop: 2},
+				{PID: 2, Start: 2, Stop: 4},
+				{PID: 1, Start: 4, Stop: 6},
+			},
+		},
+	}
+	for _, tt := range tests {
+		NewArrivalsFirst = tt.newArrivalsFirst
+		got := RR("Round-robin", processes, 2, 0)
+		if !reflect.DeepEqual(got.Gantt, tt.wantGantt) {
+			t.Errorf("RR() new arrivals first %v gantt = %v, want %v", tt.newArrivalsFirst, got.Gantt, tt.wantGantt)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	t.Cleanup(func() { Trace = nil })
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 3, Priority: 1},
+		{ProcessID: 2, ArrivalTime: 1, BurstDuration: 2, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 6, BurstDuration: 1, Priority: 1},
+	}
+	tests := []struct {
+		name     string
+		schedule func()
+		want     string
+	}{
+		{
+			name:     "FCFS",
+			schedule: func() { FCFS("FCFS", processes, 0) },
+			want: "t=0 ready=[1] run PID 1\n" +
+				"t=3 ready=[2] run PID 2\n" +
+				"t=5 ready=[] idle\n" +
+				"t=6 ready=[3] run PID 3\n",
+		},
+		{
+			name:     "RR",
+			schedule: func() { RR("RR", processes, 2, 0) },
+			want: "t=0 ready=[1] run PID 1\n" +
+				"t=2 ready=[1 2] run PID 1\n" +
+				"t=3 ready=[2] run PID 2\n" +
+				"t=5 ready=[] idle\n" +
+				"t=6 ready=[3] run PID 3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			Trace = &w
+			tt.schedule()
+			if got := w.String(); got != tt.want {
+				t.Errorf("trace = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_contextSwitch(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		gantt     []TimeSlice
+		pid       int64
+		cost      int64
+		wantGantt []TimeSlice
+		wantTime  int64
+	}{
+		{
+			name:      "first dispatch",
+			pid:       1,
+			cost:      2,
+			wantGantt: nil,
+			wantTime:  0,
+		},
+		{
+			name:      "same process",
+			gantt:     []TimeSlice{{PID: 1, Start: 0, Stop: 4}},
+			pid:       1,
+			cost:      2,
+			wantGantt: []TimeSlice{{PID: 1, Start: 0, Stop: 4}},
+			wantTime:  4,
+		},
+		{
+			name:      "after idle",
+			gantt:     []TimeSlice{{PID: IdlePID, Start: 0, Stop: 4}},
+			pid:       1,
+			cost:      2,
+			wantGantt: []TimeSlice{{PID: IdlePID, Start: 0, Stop: 4}},
+			wantTime:  4,
+		},
+		{
+			name:      "free switch",
+			gantt:     []TimeSlice{{PID: 1, Start: 0, Stop: 4}},
+			pid:       2,
+			wantGantt: []TimeSlice{{PID: 1, Start: 0, Stop: 4}},
+			wantTime:  4,
+		},
+		{
+			name:      "switch",
+			gantt:     []TimeSlice{{PID: 1, Start: 0, Stop: 4}},
+			pid:       2,
+			cost:      2,
+			wantGantt: []TimeSlice{{PID: 1, Start: 0, Stop: 4}, {PID: OverheadPID, Start: 4, Stop: 6}},
+			wantTime:  6,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var currentTime int64
+			if len(tt.gantt) > 0 {
+				currentTime = tt.gantt[len(tt.gantt)-1].Stop
+			}
+			gotGantt, gotTime := contextSwitch(tt.gantt, tt.pid, currentTime, tt.cost)
+			if !reflect.DeepEqual(gotGantt, tt.wantGantt) {
+				t.Errorf("contextSwitch() gantt = %v, want %v", gotGantt, tt.wantGantt)
+			}
+			if gotTime != tt.wantTime {
+				t.Errorf("contextSwitch() time = %v, want %v", gotTime, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestMLFQSchedule(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		processes     []Process
+		title         string
+		quanta        []int64
+		agingInterval int64
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut string
+	}{
+		{
+			name: "process using its full quantum is demoted",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 5,
+						Priority:      1,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   1,
+						BurstDuration: 2,
+						Priority:      1,
+					},
+				},
+				title:  "Multilevel feedback queue",
+				quanta: []int64{2, 4},
+			},
+			wantOut: loadFixture(t, "mlfq_test.txt"),
+		},
+		{
+			name: "waiting process is aged back up",
+			args: args{
+				processes: []Process{
+					{
+						ProcessID:     1,
+						ArrivalTime:   0,
+						BurstDuration: 8,
+						Priority:      1,
+					},
+					{
+						ProcessID:     2,
+						ArrivalTime:   2,
+						BurstDuration: 2,
+						Priority:      1,
+					},
+					{
+						ProcessID:     3,
+						ArrivalTime:   4,
+						BurstDuration: 2,
+						Priority:      1,
+					},
+					{
+						ProcessID:     4,
+						ArrivalTime:   6,
+						BurstDuration: 2,
+						Priority:      1,
+					},
+					{
+						ProcessID:     5,
+						ArrivalTime:   8,
+						BurstDuration: 2,
+						Priority:      1,
+					},
+				},
+				title:         "Multilevel feedback queue",
+				quanta:        []int64{2, 2},
+				agingInterval: 3,
+			},
+			wantOut: loadFixture(t, "mlfq_aging_test.txt"),
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			MLFQSchedule(&w, tt.args.title, tt.args.processes, tt.args.quanta, tt.args.agingInterval, 0)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("MLFQSchedule() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestLottery(t *testing.T) {
+	t.Parallel()
+	got := Lottery("Lottery", []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 4, Priority: 1},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 4, Priority: 3},
+		{ProcessID: 3, ArrivalTime: 1, BurstDuration: 2, Priority: 2},
+	}, 1, 7, 0)
+	want := []TimeSlice{
+		{PID: 2, Start: 0, Stop: 1},
+		{PID: 1, Start: 1, Stop: 2},
+		{PID: 3, Start: 2, Stop: 3},
+		{PID: 2, Start: 3, Stop: 5},
+		{PID: 3, Start: 5, Stop: 6},
+		{PID: 2, Start: 6, Stop: 7},
+		{PID: 1, Start: 7, Stop: 10},
+	}
+	if !reflect.DeepEqual(got.Gantt, want) {
+		t.Errorf("Lottery() Gantt = %v, want %v", got.Gantt, want)
+	}
+
+	// with four times the tickets, process 2 should get about four fifths of the CPU until it is done.
+	got = Lottery("Lottery", []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 1000, Priority: 1},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 1000, Priority: 4},
+	}, 1, 1, 0)
+	done := got.Stats[1].Exit
+	var ran int64
+	for _, slice := range got.Gantt {
+		if slice.PID == 1 && slice.Stop <= done {
+			ran += slice.Stop - slice.Start
+		}
+	}
+	if share := float64(ran) / float64(done); share < 0.15 || share > 0.25 {
+		t.Errorf("Lottery() process 1 got %v of the CPU while process 2 ran, want about 0.2", share)
+	}
+}
+
+func Test_scheduleAll(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+	}
+	tests := []struct {
+		name       string
+		algos      []string
+		preemptive bool
+		want       []string
+	}{
+		{
+			name: "all",
+			want: []string{
+				"First-come, first-serve",
+				"Shortest-job-first",
+				"Shortest-remaining-time-first",
+				"Longest-job-first",
+				"Longest-remaining-time-first",
+				"Preemptive priority",
+				"Priority",
+				"Round-robin",
+				"Multilevel feedback queue",
+				"Lottery",
+			},
+		},
+		{
+			name:  "subset in the given order",
+			algos: []string{"rr", "fcfs"},
+			want:  []string{"Round-robin", "First-come, first-serve"},
+		},
+		{
+			name:  "non-preemptive families",
+			algos: []string{"sjf", "priority"},
+			want:  []string{"Shortest-job-first", "Priority"},
+		},
+		{
+			name:       "preemptive families",
+			algos:      []string{"sjf", "priority"},
+			preemptive: true,
+			want:       []string{"Shortest-remaining-time-first", "Preemptive priority"},
+		},
+		{
+			name:       "preemptive leaves out repeats",
+			preemptive: true,
+			want: []string{
+				"First-come, first-serve",
+				"Shortest-remaining-time-first",
+				"Longest-job-first",
+				"Longest-remaining-time-first",
+				"Preemptive priority",
+				"Round-robin",
+				"Multilevel feedback queue",
+				"Lottery",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			opts := options{quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, cores: 1, algos: tt.algos, preemptive: tt.preemptive}
+			var got []string
+			for _, r := range scheduleAll(opts, processes) {
+				got = append(got, r.Title)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scheduleAll() titles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulersLeaveProcessesUnchanged(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 6, BurstDuration: 6, Priority: 3},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 9, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 3, BurstDuration: 5, Priority: 2},
+	}
+	want := append([]Process(nil), processes...)
+
+	FCFSSchedule(io.Discard, "First-come, first-serve", processes, 0)
+	FCFSScheduleMulti(io.Discard, "First-come, first-serve", processes, 2, 0)
+	SJFSchedule(io.Discard, "Shortest-job-first", processes, 0)
+	SRTFSchedule(io.Discard, "Shortest-remaining-time-first", processes, 0)
+	LJFSchedule(io.Discard, "Longest-job-first", processes, 0)
+	LRTFSchedule(io.Discard, "Longest-remaining-time-first", processes, 0)
+	PreemptivePrioritySchedule(io.Discard, "Preemptive priority", processes, 0, 0)
+	SJFPrioritySchedule(io.Discard, "Priority", processes, 0, 0)
+	RRSchedule(io.Discard, "Round-robin", processes, defaultQuantum, 0)
+	MLFQSchedule(io.Discard, "Multilevel feedback queue", processes, []int64{2, 4, 8}, 5, 0)
+	LotterySchedule(io.Discard, "Lottery", processes, 1, 0)
+
+	if !reflect.DeepEqual(processes, want) {
+		t.Errorf("processes = %v, want %v", processes, want)
+	}
+}
+
+func TestSchedulersRecordIdleSlices(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 1},
+		{ProcessID: 2, ArrivalTime: 10, BurstDuration: 3, Priority: 1},
+	}
+	tests := []struct {
+		name     string
+		schedule func(w io.Writer)
+	}{
+		{
+			name:     "FCFS",
+			schedule: func(w io.Writer) { FCFSSchedule(w, "FCFS", processes, 0) },
+		},
+		{
+			name:     "SJF",
+			schedule: func(w io.Writer) { SJFSchedule(w, "SJF", processes, 0) },
+		},
+		{
+			name:     "SRTF",
+			schedule: func(w io.Writer) { SRTFSchedule(w, "SRTF", processes, 0) },
+		},
+		{
+			name:     "LJF",
+			schedule: func(w io.Writer) { LJFSchedule(w, "LJF", processes, 0) },
+		},
+		{
+			name:     "LRTF",
+			schedule: func(w io.Writer) { LRTFSchedule(w, "LRTF", processes, 0) },
+		},
+		{
+			name:     "preemptive priority",
+			schedule: func(w io.Writer) { PreemptivePrioritySchedule(w, "Preemptive priority", processes, 0, 0) },
+		},
+		{
+			name:     "priority",
+			schedule: func(w io.Writer) { SJFPrioritySchedule(w, "Priority", processes, 0, 0) },
+		},
+		{
+			name:     "RR",
+			schedule: func(w io.Writer) { RRSchedule(w, "RR", processes, 10, 0) },
+		},
+		{
+			name:     "MLFQ",
+			schedule: func(w io.Writer) { MLFQSchedule(w, "MLFQ", processes, []int64{10}, 0, 0) },
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			tt.schedule(&w)
+			for _, want := range []string{"| <idle> |", "\n0       5        10      13\n"} {
+				if !strings.Contains(w.String(), want) {
+					t.Errorf("Gantt = %v, want %v", w.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestThroughputLateFirstArrival(t *testing.T) {
+	t.Parallel()
+	// every algorithm keeps the CPU busy from the first arrival at 10 to the last completion at 19.
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 10, BurstDuration: 4, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 11, BurstDuration: 2, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 12, BurstDuration: 3, Priority: 3},
+	}
+	results := []ScheduleResult{
+		FCFS("FCFS", processes, 0),
+		SJF("SJF", processes, 0),
+		SRTF("SRTF", processes, 0),
+		LJF("LJF", processes, 0),
+		LRTF("LRTF", processes, 0),
+		SJFPriority("Priority", processes, 0, 0),
+		PreemptivePriority("Preemptive priority", processes, 0, 0),
+		RR("Round-robin", processes, 2, 0),
+	}
+	for _, r := range results {
+		if r.Makespan != 9 {
+			t.Errorf("%s makespan = %d, want 9", r.Title, r.Makespan)
+		}
+		if want := 3.0 / 9; r.Throughput != want {
+			t.Errorf("%s throughput = %v, want %v over the makespan rather than from time 0", r.Title, r.Throughput, want)
+		}
+	}
+}
+
+func TestTiedWorkloadDeterministic(t *testing.T) {
+	t.Parallel()
+	// every process ties on arrival, burst, and priority, so only the tie-break orders them.
+	processes := []Process{
+		{ProcessID: 3, ArrivalTime: 0, BurstDuration: 3, Priority: 1},
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 3, Priority: 1},
+		{ProcessID: 4, ArrivalTime: 0, BurstDuration: 3, Priority: 1},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 3, Priority: 1},
+	}
+	opts, _, err := parseFlags("binary_name")
+	if err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+	render := func() string {
+		var b bytes.Buffer
+		for _, r := range scheduleAll(opts, processes) {
+			Render(&b, r)
+		}
+		return b.String()
+	}
+
+	want := render()
+	for i := 0; i < 100; i++ {
+		if got := render(); got != want {
+			t.Fatalf("run %d output = %q, want the same output as the first run %q", i, got, want)
+		}
+	}
+}
+
+func TestSchedulersHandleFewProcesses(t *testing.T) {
+	t.Parallel()
+	schedulers := []struct {
+		name     string
+		schedule func(processes []Process) ScheduleResult
+	}{
+		{"FCFS", func(processes []Process) ScheduleResult { return FCFS("FCFS", processes, 0) }},
+		{"FCFS multi-core", func(processes []Process) ScheduleResult { return FCFSMulti("FCFS", processes, 2, 0) }},
+		{"SJF", func(processes []Process) ScheduleResult { return SJF("SJF", processes, 0) }},
+		{"SRTF", func(processes []Process) ScheduleResult { return SRTF("SRTF", processes, 0) }},
+		{"LJF", func(processes []Process) ScheduleResult { return LJF("LJF", processes, 0) }},
+		{"LRTF", func(processes []Process) ScheduleResult { return LRTF("LRTF", processes, 0) }},
+		{"preemptive priority", func(processes []Process) ScheduleResult {
+			return PreemptivePriority("Preemptive priority", processes, 0, 0)
+		}},
+		{"priority", func(processes []Process) ScheduleResult { return SJFPriority("Priority", processes, 0, 0) }},
+		{"RR", func(processes []Process) ScheduleResult { return RR("RR", processes, 2, 0) }},
+		{"MLFQ", func(processes []Process) ScheduleResult { return MLFQ("MLFQ", processes, []int64{2, 4}, 0, 0) }},
+		{"lottery", func(processes []Process) ScheduleResult { return Lottery("Lottery", processes, 2, 1, 0) }},
+	}
+	for _, sc := range schedulers {
+		sc := sc
+		t.Run(sc.name+"/empty", func(t *testing.T) {
+			t.Parallel()
+			got := sc.schedule(nil)
+			for _, metric := range []float64{got.AvgWait, got.AvgTurnaround, got.AvgResponse, got.Throughput, got.Utilization} {
+				if metric != 0 {
+					t.Errorf("metrics = %v, want all zero", got)
+				}
+			}
+			var w bytes.Buffer
+			Render(&w, got)
+			if !strings.Contains(w.String(), noProcesses) || strings.Contains(w.String(), "NaN") {
+				t.Errorf("Render() = %q, want %q", w.String(), noProcesses)
+			}
+		})
+		t.Run(sc.name+"/single", func(t *testing.T) {
+			t.Parallel()
+			got := sc.schedule([]Process{{ProcessID: 1, ArrivalTime: 2, BurstDuration: 3, Priority: 1}})
+			want := ProcessStats{
+				Process:    Process{ProcessID: 1, ArrivalTime: 2, BurstDuration: 3, Priority: 1},
+				Turnaround: 3,
+				Exit:       5,
+			}
+			if len(got.Stats) != 1 || got.Stats[0] != want {
+				t.Errorf("Stats = %v, want [%v]", got.Stats, want)
+			}
+			// throughput and utilization are over the makespan from the arrival, leaving out the idle start.
+			if got.AvgWait != 0 || got.AvgTurnaround != 3 || got.Throughput != 1.0/3 || got.Utilization != 1 {
+				t.Errorf("metrics = %v, want no wait, turnaround 3, throughput 1/3 and utilization 1", got)
+			}
+		})
+	}
+}
+
+func Test_higherPriority(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		a, b Process
+		want bool
+	}{
+		{
+			name: "lower priority number wins",
+			a:    Process{ProcessID: 2, ArrivalTime: 5, Priority: 1},
+			b:    Process{ProcessID: 1, ArrivalTime: 0, Priority: 2},
+			want: true,
+		},
+		{
+			name: "tie on priority goes to earlier arrival",
+			a:    Process{ProcessID: 2, ArrivalTime: 1, Priority: 1},
+			b:    Process{ProcessID: 1, ArrivalTime: 3, Priority: 1},
+			want: true,
+		},
+		{
+			name: "tie on priority and arrival goes to lower PID",
+			a:    Process{ProcessID: 2, ArrivalTime: 1, Priority: 1},
+			b:    Process{ProcessID: 1, ArrivalTime: 1, Priority: 1},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := higherPriority(tt.a, tt.b); got != tt.want {
+				t.Errorf("higherPriority() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHigherNumberIsHigherPriority is not parallel because it replaces the package's priority convention.
+func TestHigherNumberIsHigherPriority(t *testing.T) {
+	t.Cleanup(func() { HigherNumberIsHigherPriority = false })
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 2, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 1, BurstDuration: 3, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 1, BurstDuration: 3, Priority: 3},
+	}
+	tests := []struct {
+		higherNumber   bool
+		wantPreemptive []int64
+		wantPriority   []int64
+	}{
+		{
+			higherNumber:   false,
+			wantPreemptive: []int64{1, 2, 1, 3},
+			wantPriority:   []int64{1, 2, 3},
+		},
+		{
+			higherNumber:   true,
+			wantPreemptive: []int64{1, 3, 1, 2},
+			wantPriority:   []int64{1, 3, 2},
+		},
+	}
+	for _, tt := range tests {
+		HigherNumberIsHigherPriority = tt.higherNumber
+		preemptive := PreemptivePriority("Preemptive priority", processes, 0, 0)
+		if got := runOrder(preemptive.Gantt); !reflect.DeepEqual(got, tt.wantPreemptive) {
+			t.Errorf("PreemptivePriority() higher number %v run order = %v, want %v", tt.higherNumber, got, tt.wantPreemptive)
+		}
+		priority := SJFPriority("Priority", processes, 0, 0)
+		if got := runOrder(priority.Gantt); !reflect.DeepEqual(got, tt.wantPriority) {
+			t.Errorf("SJFPriority() higher number %v run order = %v, want %v", tt.higherNumber, got, tt.wantPriority)
+		}
+		// the table still shows each process's own priority.
+		for i, row := range priority.Rows {
+			if want := strconv.FormatInt(processes[i].Priority, 10); row[1] != want {
+				t.Errorf("SJFPriority() higher number %v row %d priority = %v, want %v", tt.higherNumber, i, row[1], want)
+			}
+		}
+	}
+}
+
+func Test_arrivalPriorityPID(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		a, b Process
+		want bool
+	}{
+		{
+			name: "earlier arrival wins",
+			a:    Process{ProcessID: 2, ArrivalTime: 1, Priority: 3},
+			b:    Process{ProcessID: 1, ArrivalTime: 2, Priority: 1},
+			want: true,
+		},
+		{
+			name: "tie on arrival goes to lower priority number",
+			a:    Process{ProcessID: 2, ArrivalTime: 1, Priority: 3},
+			b:    Process{ProcessID: 1, ArrivalTime: 1, Priority: 1},
+			want: false,
+		},
+		{
+			name: "tie on arrival and priority goes to lower PID",
+			a:    Process{ProcessID: 1, ArrivalTime: 1, Priority: 1},
+			b:    Process{ProcessID: 2, ArrivalTime: 1, Priority: 1},
+			want: true,
+		},
+		{
+			name: "identical processes do not beat each other",
+			a:    Process{ProcessID: 1, ArrivalTime: 1, Priority: 1},
+			b:    Process{ProcessID: 1, ArrivalTime: 1, Priority: 1},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := arrivalPriorityPID(tt.a, tt.b); got != tt.want {
+				t.Errorf("arrivalPriorityPID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// tiedProcesses all arrive together with the same burst, so only the tie-break decides their order.
+var tiedProcesses = []Process{
+	{ProcessID: 3, ArrivalTime: 0, BurstDuration: 4, Priority: 2},
+	{ProcessID: 2, ArrivalTime: 0, BurstDuration: 4, Priority: 1},
+	{ProcessID: 1, ArrivalTime: 0, BurstDuration: 4, Priority: 2},
+}
+
+// runOrder is the PIDs of the slices in a Gantt chart.
+func runOrder(gantt []TimeSlice) []int64 {
+	pids := make([]int64, len(gantt))
+	for i := range gantt {
+		pids[i] = gantt[i].PID
+	}
+	return pids
+}
+
+func TestSchedulersBreakTies(t *testing.T) {
+	t.Parallel()
+	// equal arrival goes to the lower priority number, then the lower PID.
+	want := []int64{2, 1, 3}
+	for name, result := range map[string]ScheduleResult{
+		"SJF":      SJF("SJF", tiedProcesses, 0),
+		"SRTF":     SRTF("SRTF", tiedProcesses, 0),
+		"LJF":      LJF("LJF", tiedProcesses, 0),
+		"Priority": SJFPriority("Priority", tiedProcesses, 0, 0),
+	} {
+		if got := runOrder(result.Gantt); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s run order = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// TestTieBreakOverride is not parallel because it replaces the package's TieBreak.
+func TestTieBreakOverride(t *testing.T) {
+	defaultTieBreak := TieBreak
+	t.Cleanup(func() { TieBreak = defaultTieBreak })
+	TieBreak = tieBreaks["pid"]
+
+	want := []int64{1, 2, 3}
+	for name, result := range map[string]ScheduleResult{
+		"SJF":  SJF("SJF", tiedProcesses, 0),
+		"SRTF": SRTF("SRTF", tiedProcesses, 0),
+	} {
+		if got := runOrder(result.Gantt); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s run order = %v, want %v", name, got, want)
+		}
+	}
+	// priority still comes first, only the tie between PIDs 1 and 3 is decided by TieBreak.
+	if got, want := runOrder(SJFPriority("Priority", tiedProcesses, 0, 0).Gantt), []int64{2, 1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Priority run order = %v, want %v", got, want)
+	}
+}
+
+func Test_outputGantt(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		gantt   []TimeSlice
+		wantOut string
+	}{
+		{
+			name: "four processes with multi-digit IDs and times",
+			gantt: []TimeSlice{
+				{PID: 1, Start: 0, Stop: 8},
+				{PID: 12, Start: 8, Stop: 15},
+				{PID: IdlePID, Start: 15, Stop: 20},
+				{PID: 103, Start: 20, Stop: 1250},
+				{PID: 4, Start: 1250, Stop: 100000},
+			},
+			wantOut: loadFixture(t, "gantt_test.txt"),
+		},
+		{
+			name:    "empty",
+			wantOut: "Gantt schedule\n|\n\n\n",
+		},
+		{
+			name: "one row per core",
+			gantt: []TimeSlice{
+				{PID: 1, Start: 0, Stop: 5, Core: 1},
+				{PID: 2, Start: 0, Stop: 3, Core: 2},
+				{PID: 3, Start: 3, Stop: 9, Core: 2},
+			},
+			wantOut: "Gantt schedule\n" +
+				"Core 1\n|   1   |\n0       5\n\n" +
+				"Core 2\n|   2   |   3   |\n0       3       9\n\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w bytes.Buffer
+			outputGantt(&w, tt.gantt)
+			if got := w.String(); got != tt.wantOut {
+				t.Errorf("outputGantt() = %v, want %v", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func Test_mergeSlices(t *testing.T) {
+	t.Parallel()
+	// once process 1 finishes, process 2 is the only one left and runs quantum after quantum.
+	result := RR("Round-robin", []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 2},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 10},
+	}, 2, 0)
+	raw := append([]TimeSlice(nil), result.Gantt...)
+	want := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 2},
+		{PID: 2, Start: 2, Stop: 12},
+	}
+
+	if got := mergeSlices(result.Gantt); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeSlices() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(result.Gantt, raw) {
+		t.Errorf("mergeSlices() changed its input to %v", result.Gantt)
+	}
+
+	// an idle gap, a different level, or a different core keeps slices apart.
+	apart := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 2},
+		{PID: 1, Start: 3, Stop: 5},
+		{PID: 1, Start: 5, Stop: 6, Level: 2},
+		{PID: 1, Start: 6, Stop: 7, Level: 2, Core: 1},
+	}
+	if got := mergeSlices(apart); !reflect.DeepEqual(got, apart) {
+		t.Errorf("mergeSlices() = %v, want %v", got, apart)
+	}
+}
+
+// TestColorGantt is not parallel because it replaces the package's ColorGantt.
+func TestColorGantt(t *testing.T) {
+	t.Cleanup(func() { ColorGantt = false })
+	gantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 5},
+		{PID: IdlePID, Start: 5, Stop: 6},
+		{PID: 2, Start: 6, Stop: 8},
+	}
+
+	var plain bytes.Buffer
+	outputGantt(&plain, gantt)
+	if strings.Contains(plain.String(), "\x1b[") {
+		t.Errorf("outputGantt() = %q, want no color codes when off", plain.String())
+	}
+
+	ColorGantt = true
+	var colored bytes.Buffer
+	outputGantt(&colored, gantt)
+	for _, want := range []string{"\x1b[32m1\x1b[0m", "\x1b[33m2\x1b[0m", " <idle> "} {
+		if !strings.Contains(colored.String(), want) {
+			t.Errorf("outputGantt() = %q, want it to contain %q", colored.String(), want)
+		}
+	}
+	// the codes take no room, so the time row still lines up with the plain chart.
+	if got, want := strings.Split(colored.String(), "\n")[2], strings.Split(plain.String(), "\n")[2]; got != want {
+		t.Errorf("outputGantt() time row = %q, want %q", got, want)
+	}
+}
+
+func Test_outputReadyQueues(t *testing.T) {
+	t.Parallel()
+	// PID 3 arrives while PID 1 runs its first quantum and queues behind PID 2.
+	r := RR("Round-robin", []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 4},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 4},
+		{ProcessID: 3, ArrivalTime: 1, BurstDuration: 2},
+	}, 2, 0)
+
+	var b bytes.Buffer
+	outputReadyQueues(&b, r)
+	lines := strings.Split(b.String(), "\n")
+	if lines[0] != "Ready queue" {
+		t.Fatalf("outputReadyQueues() = %q, want it to start with the Ready queue heading", b.String())
+	}
+	if got, want := lines[1], "0-2\t1\twaiting: 2"; got != want {
+		t.Errorf("outputReadyQueues() first slice = %q, want %q", got, want)
+	}
+	if got, want := lines[2], "2-4\t2\twaiting: 1, 3"; got != want {
+		t.Errorf("outputReadyQueues() second slice = %q, want %q", got, want)
+	}
+}
+
+func Test_waitingAt(t *testing.T) {
+	t.Parallel()
+	stats := []ProcessStats{
+		{Process: Process{ProcessID: 1, ArrivalTime: 0}, Exit: 4},
+		{Process: Process{ProcessID: 2, ArrivalTime: 0}, Exit: 6},
+		{Process: Process{ProcessID: 3, ArrivalTime: 5}, Exit: 8},
+	}
+	gantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 4},
+		{PID: 2, Start: 4, Stop: 6},
+		{PID: 3, Start: 6, Stop: 8},
+	}
+	tests := []struct {
+		now  int64
+		want []int64
+	}{
+		{now: 0, want: []int64{2}},
+		{now: 4, want: nil},
+		{now: 5, want: []int64{3}},
+		{now: 8, want: nil},
+	}
+	for _, tt := range tests {
+		if got := waitingAt(stats, gantt, tt.now); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("waitingAt(%d) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func Test_isTTY(t *testing.T) {
+	t.Parallel()
+	f, err := os.CreateTemp(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+
+	if isTTY(f) {
+		t.Errorf("isTTY() = true for a regular file")
+	}
+}
+
+func Test_newScheduleResult(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 8, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 1, BurstDuration: 4, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 2, BurstDuration: 2, Priority: 3},
+	}
+	completion := []int64{14, 7, 4}
+	gantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 1},
+		{PID: 2, Start: 1, Stop: 2},
+		{PID: 3, Start: 2, Stop: 4},
+		{PID: 2, Start: 4, Stop: 7},
+		{PID: 1, Start: 7, Stop: 14},
+	}
+	wantRows := [][]string{
+		{"1", "2", "8", "0", "6", "14", "1.75", "0", "14"},
+		{"2", "1", "4", "1", "2", "6", "1.50", "0", "7"},
+		{"3", "3", "2", "2", "0", "2", "1.00", "0", "4"},
+	}
+
+	got := newScheduleResult("title", processStats(processes, completion, gantt), gantt)
+	if !reflect.DeepEqual(got.Rows, wantRows) {
+		t.Errorf("newScheduleResult() rows = %v, want %v", got.Rows, wantRows)
+	}
+	if want := 8.0 / 3; got.AvgWait != want {
+		t.Errorf("newScheduleResult() wait = %v, want %v", got.AvgWait, want)
+	}
+	if want := 22.0 / 3; got.AvgTurnaround != want {
+		t.Errorf("newScheduleResult() turnaround = %v, want %v", got.AvgTurnaround, want)
+	}
+	if want := 0.0; got.AvgResponse != want {
+		t.Errorf("newScheduleResult() response = %v, want %v", got.AvgResponse, want)
+	}
+	if want := (1.75 + 1.5 + 1) / 3; got.AvgNormalizedTurnaround != want {
+		t.Errorf("newScheduleResult() normalized turnaround = %v, want %v", got.AvgNormalizedTurnaround, want)
+	}
+	if want := 3.0 / 14; got.Throughput != want {
+		t.Errorf("newScheduleResult() throughput = %v, want %v", got.Throughput, want)
+	}
+}
+
+func Test_normalizedTurnaround(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		st   ProcessStats
+		want float64
+	}{
+		{name: "no wait", st: ProcessStats{Process: Process{BurstDuration: 4}, Turnaround: 4}, want: 1},
+		{name: "waited", st: ProcessStats{Process: Process{BurstDuration: 4}, Turnaround: 10}, want: 2.5},
+		{name: "no burst", st: ProcessStats{Turnaround: 3}, want: 0},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := normalizedTurnaround(tt.st); got != tt.want {
+				t.Errorf("normalizedTurnaround() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFCFS(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 6, BurstDuration: 6, Priority: 3},
+	}
+	wantGantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 5},
+		{PID: 2, Start: 5, Stop: 14},
+		{PID: 3, Start: 14, Stop: 20},
+	}
+
+	got := FCFS("First-come, first-serve", processes, 0)
+	if got.Title != "First-come, first-serve" {
+		t.Errorf("FCFS() title = %v, want %v", got.Title, "First-come, first-serve")
+	}
+	if !reflect.DeepEqual(got.Gantt, wantGantt) {
+		t.Errorf("FCFS() gantt = %v, want %v", got.Gantt, wantGantt)
+	}
+	if want := 10.0 / 3; got.AvgWait != want {
+		t.Errorf("FCFS() wait = %v, want %v", got.AvgWait, want)
+	}
+	if want := 30.0 / 3; got.AvgTurnaround != want {
+		t.Errorf("FCFS() turnaround = %v, want %v", got.AvgTurnaround, want)
+	}
+	if want := 3.0 / 20; got.Throughput != want {
+		t.Errorf("FCFS() throughput = %v, want %v", got.Throughput, want)
+	}
+}
+
+func TestFCFSSortsByArrival(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 3, ArrivalTime: 6, BurstDuration: 6},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 9},
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5},
+		{ProcessID: 4, ArrivalTime: 30, BurstDuration: 2},
+	}
+	input := append([]Process(nil), processes...)
+	// processes arriving together run in PID order, and the CPU idles until the late arrival.
+	wantGantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 5},
+		{PID: 2, Start: 5, Stop: 14},
+		{PID: 3, Start: 14, Stop: 20},
+		{PID: IdlePID, Start: 20, Stop: 30},
+		{PID: 4, Start: 30, Stop: 32},
+	}
+	wantWait := map[int64]int64{1: 0, 2: 5, 3: 8, 4: 0}
+
+	got := FCFS("First-come, first-serve", processes, 0)
+	if !reflect.DeepEqual(got.Gantt, wantGantt) {
+		t.Errorf("FCFS() gantt = %v, want %v", got.Gantt, wantGantt)
+	}
+	for _, stat := range got.Stats {
+		if stat.Wait != wantWait[stat.ProcessID] {
+			t.Errorf("FCFS() PID %d wait = %d, want %d", stat.ProcessID, stat.Wait, wantWait[stat.ProcessID])
+		}
+		if stat.Turnaround != stat.Wait+stat.BurstDuration {
+			t.Errorf("FCFS() PID %d turnaround = %d, want wait + burst %d", stat.ProcessID, stat.Turnaround, stat.Wait+stat.BurstDuration)
+		}
+	}
+	if !reflect.DeepEqual(processes, input) {
+		t.Errorf("FCFS() reordered its input: %v", processes)
+	}
+}
+
+func TestFCFSIdleGap(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 4},
+		{ProcessID: 2, ArrivalTime: 10, BurstDuration: 3},
+		{ProcessID: 3, ArrivalTime: 11, BurstDuration: 2},
+	}
+	// the process after the gap starts on arrival, and the one after it waits only for that process.
+	want := []ProcessStats{
+		{Process: processes[0], Wait: 0, Turnaround: 4, Response: 0, Exit: 4},
+		{Process: processes[1], Wait: 0, Turnaround: 3, Response: 0, Exit: 13},
+		{Process: processes[2], Wait: 2, Turnaround: 4, Response: 2, Exit: 15},
+	}
+	wantGantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 4},
+		{PID: IdlePID, Start: 4, Stop: 10},
+		{PID: 2, Start: 10, Stop: 13},
+		{PID: 3, Start: 13, Stop: 15},
+	}
+
+	got := FCFS("First-come, first-serve", processes, 0)
+	if !reflect.DeepEqual(got.Stats, want) {
+		t.Errorf("FCFS() stats = %+v, want %+v", got.Stats, want)
+	}
+	if !reflect.DeepEqual(got.Gantt, wantGantt) {
+		t.Errorf("FCFS() gantt = %v, want %v", got.Gantt, wantGantt)
+	}
+}
+
+func TestPreemptiveIdleGap(t *testing.T) {
+	t.Parallel()
+	// ticking through a gap this long a time unit at a time would not finish.
+	const gap = int64(1e12)
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 2},
+		{ProcessID: 2, ArrivalTime: gap, BurstDuration: 3},
+	}
+	wantGantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 2},
+		{PID: IdlePID, Start: 2, Stop: gap},
+		{PID: 2, Start: gap, Stop: gap + 3},
+	}
+	tests := []struct {
+		name     string
+		schedule func() ScheduleResult
+	}{
+		{name: "SRTF", schedule: func() ScheduleResult { return SRTF("SRTF", processes, 0) }},
+		{name: "LRTF", schedule: func() ScheduleResult { return LRTF("LRTF", processes, 0) }},
+		{name: "PreemptivePriority", schedule: func() ScheduleResult { return PreemptivePriority("Priority", processes, 0, 0) }},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := tt.schedule()
+			if !reflect.DeepEqual(got.Gantt, wantGantt) {
+				t.Errorf("%s() gantt = %v, want %v", tt.name, got.Gantt, wantGantt)
+			}
+			if got.Idle != gap-2 {
+				t.Errorf("%s() idle = %d, want %d", tt.name, got.Idle, gap-2)
+			}
+		})
+	}
+}
+
+// TestPreemptiveSchedulersScaleWithDecimals is not parallel because it replaces the package's TimeDecimals.
+func TestPreemptiveSchedulersScaleWithDecimals(t *testing.T) {
+	t.Cleanup(func() { TimeDecimals = 0 })
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 6, BurstDuration: 6, Priority: 3},
+		{ProcessID: 4, ArrivalTime: 25, BurstDuration: 4, Priority: 3},
+	}
+	// every time in ticks of 10^-maxDecimals, which would not finish if the schedulers ticked through them one at a time.
+	const scale = int64(1e9)
+	scaled := make([]Process, len(processes))
+	for i, p := range processes {
+		p.ArrivalTime *= scale
+		p.BurstDuration *= scale
+		scaled[i] = p
+	}
+	tests := []struct {
+		name     string
+		schedule func(processes []Process, scale int64) ScheduleResult
+	}{
+		{name: "LRTF", schedule: func(processes []Process, scale int64) ScheduleResult {
+			return LRTF("LRTF", processes, 0)
+		}},
+		{name: "PreemptivePriority", schedule: func(processes []Process, scale int64) ScheduleResult {
+			return PreemptivePriority("Priority", processes, 2*scale, 0)
+		}},
+		{name: "MLFQ", schedule: func(processes []Process, scale int64) ScheduleResult {
+			return MLFQ("MLFQ", processes, []int64{2 * scale, 4 * scale}, 3*scale, 0)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			TimeDecimals = 0
+			want := tt.schedule(processes, 1).Gantt
+			for i := range want {
+				want[i].Start *= scale
+				want[i].Stop *= scale
+			}
+
+			TimeDecimals = maxDecimals
+			if got := tt.schedule(scaled, scale).Gantt; !reflect.DeepEqual(got, want) {
+				t.Errorf("%s() gantt = %v, want %v", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func TestMakespan(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 2, BurstDuration: 5, Priority: 1},
+		{ProcessID: 2, ArrivalTime: 10, BurstDuration: 3, Priority: 1},
+	}
+	got := FCFS("First-come, first-serve", processes, 0)
+	if want := got.Stats[1].Exit - processes[0].ArrivalTime; got.Makespan != want {
+		t.Errorf("Makespan = %d, want %d", got.Makespan, want)
+	}
+	// the idle time before the first arrival is outside the makespan, so only the gap from 7 to 10 counts.
+	if got.Idle != 3 {
+		t.Errorf("Idle = %d, want 3", got.Idle)
+	}
+}
+
+func Test_idleTime(t *testing.T) {
+	t.Parallel()
+	gantt := []TimeSlice{
+		{PID: IdlePID, Start: 0, Stop: 2, Core: 1},
+		{PID: 1, Start: 2, Stop: 7, Core: 1},
+		{PID: IdlePID, Start: 7, Stop: 10, Core: 1},
+		{PID: IdlePID, Start: 0, Stop: 4, Core: 2},
+		{PID: OverheadPID, Start: 4, Stop: 5, Core: 2},
+		{PID: 2, Start: 5, Stop: 12, Core: 2},
+	}
+	if got := idleTime(gantt, 2, 12); got != 5 {
+		t.Errorf("idleTime() = %d, want 5", got)
+	}
+}
+
+func TestSummaryCompare(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 6, BurstDuration: 6, Priority: 3},
+	}
+	results := map[string]ScheduleResult{
+		"First-come, first-serve":       FCFS("First-come, first-serve", processes, 0),
+		"Shortest-remaining-time-first": SRTF("Shortest-remaining-time-first", processes, 0),
+		"Round-robin":                   RR("Round-robin", processes, 4, 0),
+	}
+	wantRows := []string{
+		"| First-come, first-serve       |         3.33 |              10.00 |   0.15/t * |       20 |    0 |",
+		"| Round-robin                   |         4.67 |              11.33 |   0.15/t * |       20 |    0 |",
+		"| Shortest-remaining-time-first |       2.67 * |             9.33 * |   0.15/t * |       20 |    0 |",
+	}
+
+	var w bytes.Buffer
+	SummaryCompare(&w, results)
+
+	var rows []string
+	for _, line := range strings.Split(w.String(), "\n") {
+		if strings.HasPrefix(line, "| ") && !strings.Contains(line, "ALGORITHM") {
+			rows = append(rows, line)
+		}
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("SummaryCompare() rows = %q, want %q", rows, wantRows)
+	}
+}
+
+func TestDetailedView(t *testing.T) {
+	t.Parallel()
+	// listed out of PID and arrival order, which round-robin sorts by arrival.
+	processes := []Process{
+		{ProcessID: 3, ArrivalTime: 6, BurstDuration: 6, Priority: 3},
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+	}
+	results := []ScheduleResult{
+		SJF("Shortest-job-first", processes, 0),
+		SRTF("Shortest-remaining-time-first", processes, 0),
+		RR("Round-robin", processes, 4, 0),
+	}
+
+	var w bytes.Buffer
+	DetailedView(&w, results)
+
+	var rows [][]string
+	for _, line := range strings.Split(w.String(), "\n") {
+		if !strings.HasPrefix(line, "|") || strings.Contains(line, "ID") {
+			continue
+		}
+		var cells []string
+		for _, cell := range strings.Split(strings.Trim(line, "|"), "|") {
+			cells = append(cells, strings.TrimSpace(cell))
+		}
+		rows = append(rows, cells)
+	}
+	if len(rows) != len(processes) {
+		t.Fatalf("DetailedView() rows = %q, want one per process", rows)
+	}
+	for i, row := range rows {
+		if want := strconv.Itoa(i + 1); row[0] != want {
+			t.Errorf("DetailedView() row %d ID = %v, want %v", i, row[0], want)
+		}
+	}
+	for col, r := range results {
+		for _, st := range r.Stats {
+			want := fmt.Sprintf("%d / %d / %d / %d", st.Wait, st.Turnaround, st.Response, st.Exit)
+			if got := rows[st.ProcessID-1][col+1]; got != want {
+				t.Errorf("DetailedView() %s PID %d = %v, want %v", r.Title, st.ProcessID, got, want)
+			}
+		}
+	}
+}
+
+func Test_outputStarvation(t *testing.T) {
+	t.Parallel()
+	// process 1 has the lowest priority and higher priority work keeps arriving until time 16.
+	result := PreemptivePriority("Preemptive priority", []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 2, Priority: 9},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 4, Priority: 1},
+		{ProcessID: 3, ArrivalTime: 4, BurstDuration: 4, Priority: 1},
+		{ProcessID: 4, ArrivalTime: 8, BurstDuration: 4, Priority: 1},
+		{ProcessID: 5, ArrivalTime: 12, BurstDuration: 4, Priority: 1},
+	}, 0, 0)
+
+	var w bytes.Buffer
+	outputStarvation(&w, result, 10)
+	if want := "Warning: Preemptive priority starved processes waiting longer than 10: PID 1 waited 16\n\n"; w.String() != want {
+		t.Errorf("outputStarvation() = %q, want %q", w.String(), want)
+	}
+
+	w.Reset()
+	outputStarvation(&w, result, 16)
+	if w.Len() != 0 {
+		t.Errorf("outputStarvation() = %q, want no warning when nothing waits longer than the threshold", w.String())
+	}
+}
+
+func Test_cpuUtilization(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		gantt       []TimeSlice
+		start, stop int64
+		want        float64
+	}{
+		{
+			name: "always busy",
+			gantt: []TimeSlice{
+				{PID: 1, Start: 0, Stop: 5},
+				{PID: 2, Start: 5, Stop: 10},
+			},
+			stop: 10,
+			want: 1,
+		},
+		{
+			name: "idle gap",
+			gantt: []TimeSlice{
+				{PID: 1, Start: 0, Stop: 5},
+				{PID: IdlePID, Start: 5, Stop: 10},
+				{PID: 2, Start: 10, Stop: 20},
+			},
+			stop: 20,
+			want: 0.75,
+		},
+		{
+			name: "idle before the first arrival",
+			gantt: []TimeSlice{
+				{PID: IdlePID, Start: 0, Stop: 10},
+				{PID: 1, Start: 10, Stop: 15},
+				{PID: IdlePID, Start: 15, Stop: 20},
+				{PID: 2, Start: 20, Stop: 25},
+			},
+			start: 10,
+			stop:  25,
+			want:  10.0 / 15,
+		},
+		{
+			name: "empty",
+			want: 0,
+		},
+		{
+			name: "two cores",
+			gantt: []TimeSlice{
+				{PID: 1, Start: 0, Stop: 10, Core: 1},
+				{PID: IdlePID, Start: 0, Stop: 5, Core: 2},
+				{PID: 2, Start: 5, Stop: 10, Core: 2},
+			},
+			stop: 10,
+			want: 0.75,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := cpuUtilization(tt.gantt, tt.start, tt.stop); got != tt.want {
+				t.Errorf("cpuUtilization() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUtilizationLateFirstArrival(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 10, BurstDuration: 5},
+		{ProcessID: 2, ArrivalTime: 20, BurstDuration: 5},
+	}
+	got := FCFS("FCFS", processes, 0)
+	if got.Makespan != 15 || got.Idle != 5 {
+		t.Fatalf("FCFS() makespan = %d, idle = %d, want 15 and 5", got.Makespan, got.Idle)
+	}
+	// busy for 10 of the 15 time units from the first arrival, as the makespan and idle time are measured.
+	if want := 10.0 / 15; math.Abs(got.Utilization-want) > 1e-9 {
+		t.Errorf("FCFS() utilization = %v, want %v", got.Utilization, want)
+	}
+}
+
+func Test_outputJSON(t *testing.T) {
+	t.Parallel()
+	gantt := []TimeSlice{
+		{PID: 1, Start: 0, Stop: 5},
+		{PID: IdlePID, Start: 5, Stop: 10},
+		{PID: 2, Start: 10, Stop: 13},
+	}
+	stats := []ProcessStats{
+		{Process: Process{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2}, Turnaround: 5, Exit: 5},
+		{Process: Process{ProcessID: 2, ArrivalTime: 10, BurstDuration: 3, Priority: 1}, Turnaround: 3, Exit: 13},
+	}
+	want := jsonReport{
+		Title: "First-come, first-serve",
+		Processes: []jsonProcess{
+			{ID: 1, Priority: 2, Burst: "5", Arrival: "0", Wait: "0", Turnaround: "5", Response: "0", Exit: "5"},
+			{ID: 2, Priority: 1, Burst: "3", Arrival: "10", Wait: "0", Turnaround: "3", Response: "0", Exit: "13"},
+		},
+		Gantt: []jsonSlice{
+			{PID: 1, Start: "0", Stop: "5"},
+			{PID: IdlePID, Start: "5", Stop: "10"},
+			{PID: 2, Start: "10", Stop: "13"},
+		},
+		AverageWait:                 0,
+		AverageTurnaround:           4,
+		AverageNormalizedTurnaround: 1,
+		AverageResponse:             0,
+		Throughput:                  2.0 / 13,
+		CPUUtilization:              8.0 / 13,
+		Makespan:                    "13",
+		Idle:                        "5",
+	}
+
+	var w bytes.Buffer
+	outputJSON(&w, newScheduleResult(want.Title, stats, gantt))
+
+	var got jsonReport
+	if err := json.Unmarshal(w.Bytes(), &got); err != nil {
+		t.Fatalf("outputJSON() wrote invalid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outputJSON() = %+v, want %+v", got, want)
+	}
+	if !strings.Contains(w.String(), `"wait":0,"turnaround":5`) {
+		t.Errorf("outputJSON() = %v, want numeric process metrics", w.String())
+	}
+}
+
+func Test_outputCSV(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 8, BurstDuration: 4, Priority: 1},
+	}
+	want := [][]string{
+		{"First-come, first-serve"},
+		{"ID", "Priority", "Burst", "Arrival", "Wait", "Turnaround", "NTaT", "Response", "Exit", "Throughput", "Utilization %"},
+		{"1", "2", "5", "0", "0", "5", "1.00", "0", "5", "", ""},
+		{"2", "1", "4", "8", "0", "4", "1.00", "0", "12", "", ""},
+		{"Average", "", "", "", "0.00", "4.50", "1.00", "0.00", "", "0.17", "75.00"},
+		{"PID", "Start", "Stop"},
+		{"1", "0", "5"},
+		{"-1", "5", "8"},
+		{"2", "8", "12"},
+	}
+
+	var w bytes.Buffer
+	outputCSV(&w, FCFS("First-come, first-serve", processes, 0))
+
+	r := csv.NewReader(&w)
+	// the sections have different numbers of columns.
+	r.FieldsPerRecord = -1
+	got, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("outputCSV() wrote invalid CSV: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outputCSV() records = %q, want %q", got, want)
+	}
+}
+
+func Test_outputMarkdown(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+	}
+	result := FCFS("First-come, first-serve", processes, 0)
+	wantLines := []string{
+		"### First-come, first-serve",
+		"```text",
+		"|   1   |   2   |",
+		"| ID | Priority | Burst | Arrival | Wait | Turnaround | NTaT | Response | Exit |",
+		"| --- | --- | --- | --- | --- | --- | --- | --- | --- |",
+		"| 1 | 2 | 5 | 0 | 0 | 5 | 1.00 | 0 | 5 |",
+		"| 2 | 1 | 9 | 3 | 2 | 11 | 1.22 | 2 | 14 |",
+		"|  |  |  | Utilization 100.00% | Average 1.00 | Average 8.00 | Average 1.11 | Average 1.00 | Throughput 0.14/t |",
+	}
+
+	var w bytes.Buffer
+	outputMarkdown(&w, result)
+
+	lines := strings.Split(w.String(), "\n")
+	for _, want := range wantLines {
+		found := false
+		for _, line := range lines {
+			if line == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("outputMarkdown() = %v, want a line %q", w.String(), want)
+		}
+	}
+	if len(result.Rows) != len(processes) {
+		t.Errorf("outputMarkdown() changed the result rows to %v", result.Rows)
+	}
+}
+
+func TestSummaryCompareMarkdown(t *testing.T) {
+	t.Parallel()
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+	}
+	want := "### Summary\n\n" +
+		"| Algorithm | Average wait | Average turnaround | Throughput | Makespan | Idle |\n" +
+		"| --- | --- | --- | --- | --- | --- |\n" +
+		"| First-come, first-serve | 1.00 * | 8.00 * | 0.14/t * | 14 | 0 |\n\n"
+
+	var w bytes.Buffer
+	SummaryCompareMarkdown(&w, map[string]ScheduleResult{
+		"First-come, first-serve": FCFS("First-come, first-serve", processes, 0),
+	})
+	if got := w.String(); got != want {
+		t.Errorf("SummaryCompareMarkdown() = %q, want %q", got, want)
+	}
+}
+
+func Test_loadProcesses(t *testing.T) {
+	t.Parallel()
+	type args struct {
+		r io.Reader
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []Process
+		wantErr error
+	}{
+		{
+			name: "bad CSV",
+			args: args{
+				r: iotest.ErrReader(io.ErrUnexpectedEOF),
+			},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name: "success",
+			args: args{
+				r: strings.NewReader(`1,5,0,2
+2,9,3,1
+3,6,3,3`),
+			},
+			want: []Process{
+				{
+					ProcessID:     1,
+					ArrivalTime:   0,
+					BurstDuration: 5,
+					Priority:      2,
+				},
+				{
+					ProcessID:     2,
+					ArrivalTime:   3,
+					BurstDuration: 9,
+					Priority:      1,
+				},
+				{
+					ProcessID:     3,
+					ArrivalTime:   3,
+					BurstDuration: 6,
+					Priority:      3,
+				},
+			},
+		},
+		{
+			name: "two column row",
+			args: args{
+				r: strings.NewReader(`1,5,0,2
+2,9`),
+			},
+			wantErr: ErrInvalidRow,
+		},
+		{
+			name: "empty trailing lines are skipped",
+			args: args{
+				r: strings.NewReader("1,5,0,2\n\n,,,\n"),
+			},
+			want: []Process{
+				{
+					ProcessID:     1,
+					ArrivalTime:   0,
+					BurstDuration: 5,
+					Priority:      2,
+				},
+			},
+		},
+		{
+			name: "three column row has no priority",
+			args: args{
+				r: strings.NewReader(`1,5,0,2
+2,9,3`),
+			},
+			want: []Process{
+				{
+					ProcessID:     1,
+					ArrivalTime:   0,
+					BurstDuration: 5,
+					Priority:      2,
+				},
+				{
+					ProcessID:     2,
+					ArrivalTime:   3,
+					BurstDuration: 9,
+				},
+			},
+		},
+		{
+			name: "non-numeric value",
+			args: args{
+				r: strings.NewReader(`1,five,0,2`),
+			},
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name: "empty value",
+			args: args{
+				r: strings.NewReader(`1,5,,2`),
+			},
+			wantErr: strconv.ErrSyntax,
+		},
+		{
+			name: "overflowing value",
+			args: args{
+				r: strings.NewReader(`1,5,0,99999999999999999999`),
+			},
+			wantErr: strconv.ErrRange,
+		},
+		{
+			name: "header row",
+			args: args{
+				r: strings.NewReader(`ProcessID,BurstDuration,ArrivalTime,Priority
+1,5,0,2
+2,9,3,1`),
+			},
+			want: []Process{
+				{
+					ProcessID:     1,
+					ArrivalTime:   0,
+					BurstDuration: 5,
+					Priority:      2,
+				},
+				{
+					ProcessID:     2,
+					ArrivalTime:   3,
+					BurstDuration: 9,
+					Priority:      1,
+				},
+			},
+		},
+		{
+			name: "header row with columns in a different order",
+			args: args{
+				r: strings.NewReader(`Priority, Arrival Time, PID, Burst
+2,0,1,5
+1,3,2,9`),
+			},
+			want: []Process{
+				{
+					ProcessID:     1,
+					ArrivalTime:   0,
+					BurstDuration: 5,
+					Priority:      2,
+				},
+				{
+					ProcessID:     2,
+					ArrivalTime:   3,
+					BurstDuration: 9,
+					Priority:      1,
+				},
+			},
+		},
+		{
+			name: "header row without priority",
+			args: args{
+				r: strings.NewReader(`arrival_time,process_id,burst_duration
+3,2,9`),
+			},
+			want: []Process{
+				{
+					ProcessID:     2,
+					ArrivalTime:   3,
+					BurstDuration: 9,
+				},
+			},
+		},
+		{
+			name: "header row missing a column",
+			args: args{
+				r: strings.NewReader(`ProcessID,Priority
+1,2`),
+			},
+			wantErr: ErrInvalidRow,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := loadProcesses(tt.args.r)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadProcesses() = %v, want %v", got, tt.want)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_validateProcesses(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		processes []Process
+		wantErr   error
+		wantMsg   string
+	}{
+		{
+			name: "valid",
+			processes: []Process{
+				{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+				{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+			},
+		},
+		{
+			name:      "no processes",
+			processes: nil,
+		},
+		{
+			name: "zero burst",
+			processes: []Process{
+				{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5},
+				{ProcessID: 2, ArrivalTime: 3, BurstDuration: 0},
+			},
+			wantErr: ErrInvalidRow,
+			wantMsg: "row 2: burst duration must be positive, got 0",
+		},
+		{
+			name: "negative burst",
+			processes: []Process{
+				{ProcessID: 1, ArrivalTime: 0, BurstDuration: -5},
+			},
+			wantErr: ErrInvalidRow,
+			wantMsg: "row 1: burst duration must be positive, got -5",
+		},
+		{
+			name: "negative arrival",
+			processes: []Process{
+				{ProcessID: 1, ArrivalTime: -1, BurstDuration: 5},
+			},
+			wantErr: ErrInvalidRow,
+			wantMsg: "row 1: arrival time must not be negative, got -1",
+		},
+		{
+			name: "duplicate PID",
+			processes: []Process{
+				{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5},
+				{ProcessID: 2, ArrivalTime: 0, BurstDuration: 5},
+				{ProcessID: 1, ArrivalTime: 4, BurstDuration: 5},
+			},
+			wantErr: ErrInvalidRow,
+			wantMsg: "row 3: process ID 1 already used in row 1",
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			var w bytes.Buffer
-			FCFSSchedule(&w, tt.args.title, tt.args.processes)
-			if got := w.String(); got != tt.wantOut {
-				t.Errorf("FCFSSchedule() = %v, want %v", got, tt.wantOut)
+			err := validateProcesses(tt.processes)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateProcesses() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("validateProcesses() error = %v, want it to contain %q", err, tt.wantMsg)
 			}
 		})
 	}
 }
 
-func Test_loadProcesses(t *testing.T) {
+func TestGenerateProcesses(t *testing.T) {
 	t.Parallel()
-	type args struct {
-		r io.Reader
+	got := GenerateProcesses(100, 42, 10, 20, 5)
+	if again := GenerateProcesses(100, 42, 10, 20, 5); !reflect.DeepEqual(got, again) {
+		t.Fatalf("GenerateProcesses() is not deterministic for a fixed seed")
 	}
+	if other := GenerateProcesses(100, 43, 10, 20, 5); reflect.DeepEqual(got, other) {
+		t.Errorf("GenerateProcesses() generated the same processes for different seeds")
+	}
+	if len(got) != 100 {
+		t.Fatalf("GenerateProcesses() generated %d processes, want 100", len(got))
+	}
+	if err := validateProcesses(got); err != nil {
+		t.Errorf("GenerateProcesses() generated invalid processes: %v", err)
+	}
+	for i, p := range got {
+		if p.ProcessID != int64(i+1) {
+			t.Errorf("GenerateProcesses()[%d] ID = %d, want %d", i, p.ProcessID, i+1)
+		}
+		if i > 0 && p.ArrivalTime < got[i-1].ArrivalTime {
+			t.Errorf("GenerateProcesses()[%d] arrives at %d before the previous process", i, p.ArrivalTime)
+		}
+		if p.BurstDuration > 10 || p.ArrivalTime > 20 || p.Priority < 1 || p.Priority > 5 {
+			t.Errorf("GenerateProcesses()[%d] = %+v, out of range", i, p)
+		}
+	}
+}
+
+func Test_writeProcesses(t *testing.T) {
+	t.Parallel()
+	processes := GenerateProcesses(10, 1, 10, 20, 5)
+
+	var w bytes.Buffer
+	if err := writeProcesses(&w, processes); err != nil {
+		t.Fatalf("writeProcesses() error = %v", err)
+	}
+	got, err := loadProcesses(&w)
+	if err != nil {
+		t.Fatalf("loadProcesses() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, processes) {
+		t.Errorf("loadProcesses() = %v, want %v", got, processes)
+	}
+}
+
+func Test_parseTime(t *testing.T) {
+	t.Parallel()
 	tests := []struct {
-		name    string
-		args    args
-		want    []Process
-		wantErr error
+		s        string
+		decimals int
+		want     int64
+		wantErr  error
 	}{
-		{
-			name: "bad CSV",
-			args: args{
-				r: iotest.ErrReader(io.ErrUnexpectedEOF),
-			},
-			wantErr: io.ErrUnexpectedEOF,
-		},
-		{
-			name: "success",
-			args: args{
-				r: strings.NewReader(`1,5,0,2
-2,9,3,1
-3,6,3,3`),
-			},
-			want: []Process{
-				{
-					ProcessID:     1,
-					ArrivalTime:   0,
-					BurstDuration: 5,
-					Priority:      2,
-				},
-				{
-					ProcessID:     2,
-					ArrivalTime:   3,
-					BurstDuration: 9,
-					Priority:      1,
-				},
-				{
-					ProcessID:     3,
-					ArrivalTime:   3,
-					BurstDuration: 6,
-					Priority:      3,
-				},
-			},
-		},
+		{s: "7", want: 7},
+		{s: " 2.5 ", decimals: 1, want: 25},
+		{s: "0.3", decimals: 1, want: 3},
+		{s: ".3", decimals: 2, want: 30},
+		{s: "2", decimals: 2, want: 200},
+		{s: "-1.5", decimals: 1, want: -15},
+		{s: "2.5", wantErr: strconv.ErrSyntax},
+		{s: "2.55", decimals: 1, wantErr: strconv.ErrSyntax},
+		{s: "1.-5", decimals: 2, wantErr: strconv.ErrSyntax},
+		{s: "", decimals: 1, wantErr: strconv.ErrSyntax},
+		{s: "-.", decimals: 1, wantErr: strconv.ErrSyntax},
+		{s: "99999999999999999999", wantErr: strconv.ErrRange},
 	}
 	for _, tt := range tests {
 		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
+		t.Run(fmt.Sprintf("%q with %d decimals", tt.s, tt.decimals), func(t *testing.T) {
 			t.Parallel()
-			got, err := loadProcesses(tt.args.r)
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("loadProcesses() = %v, want %v", got, tt.want)
-			}
+			got, err := parseTime(tt.s, tt.decimals)
 			if !errors.Is(err, tt.wantErr) {
-				t.Errorf("error = %v, want %v", err, tt.wantErr)
+				t.Fatalf("parseTime() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseTime() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+// TestLotteryScheduleScalesQuantum is not parallel because it replaces the package's TimeDecimals.
+func TestLotteryScheduleScalesQuantum(t *testing.T) {
+	t.Cleanup(func() { TimeDecimals = 0 })
+	TimeDecimals = 1
+	processes := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 100, Priority: 1},
+		{ProcessID: 2, ArrivalTime: 0, BurstDuration: 100, Priority: 3},
+	}
+
+	var got, want bytes.Buffer
+	LotterySchedule(&got, "Lottery", processes, 1, 0)
+	Render(&want, Lottery("Lottery", processes, defaultQuantum*10, 1, 0))
+	if got.String() != want.String() {
+		t.Errorf("LotterySchedule() = %s\nwant a quantum of %d time units:\n%s", &got, defaultQuantum, &want)
+	}
+}
+
+// TestFractionalTimes is not parallel because it replaces the package's TimeDecimals.
+func TestFractionalTimes(t *testing.T) {
+	t.Cleanup(func() { TimeDecimals = 0 })
+	TimeDecimals = 1
+
+	processes, err := loadProcesses(strings.NewReader("1,2.5,0\n2,0.3,1\n3,1,1.2\n"))
+	if err != nil {
+		t.Fatalf("loadProcesses() error = %v", err)
+	}
+	wantProcesses := []Process{
+		{ProcessID: 1, BurstDuration: 25, ArrivalTime: 0},
+		{ProcessID: 2, BurstDuration: 3, ArrivalTime: 10},
+		{ProcessID: 3, BurstDuration: 10, ArrivalTime: 12},
+	}
+	if !reflect.DeepEqual(processes, wantProcesses) {
+		t.Fatalf("loadProcesses() = %v, want %v", processes, wantProcesses)
+	}
+
+	quantum, err := parseTime("0.5", TimeDecimals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := RR("Round-robin", processes, quantum, 0)
+	wantRows := [][]string{
+		{"1", "0", "2.5", "0.0", "0.8", "3.3", "1.32", "0.0", "3.3"},
+		{"2", "0", "0.3", "1.0", "0.5", "0.8", "2.67", "0.5", "1.8"},
+		{"3", "0", "1.0", "1.2", "1.6", "2.6", "2.60", "1.1", "3.8"},
+	}
+	if !reflect.DeepEqual(result.Rows, wantRows) {
+		t.Errorf("RR() rows = %v, want %v", result.Rows, wantRows)
+	}
+	if want := (3.3 + 0.8 + 2.6) / 3; math.Abs(result.AvgTurnaround-want) > 1e-9 {
+		t.Errorf("RR() turnaround = %v, want %v", result.AvgTurnaround, want)
+	}
+
+	var w bytes.Buffer
+	outputGantt(&w, result.Gantt)
+	if times := strings.Split(w.String(), "\n")[2]; !strings.HasPrefix(times, "0.0     1.5     1.8     2.3") {
+		t.Errorf("outputGantt() times = %q, want them in tenths", times)
+	}
+
+	var csv bytes.Buffer
+	if err := writeProcesses(&csv, processes); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(csv.String(), "1,2.5,0.0,0") {
+		t.Errorf("writeProcesses() = %q, want fractional times", csv.String())
+	}
+
+	err = validateProcesses([]Process{{ProcessID: 1, BurstDuration: 25, ArrivalTime: -15}})
+	if want := "row 1: arrival time must not be negative, got -1.5"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("validateProcesses() error = %v, want it to contain %q", err, want)
+	}
+}
+
 func loadFixture(t *testing.T, p ...string) string {
 	b, err := os.ReadFile(path.Join(p...))
 	if err != nil {
@@ -205,3 +2880,396 @@ func Test_openProcessingFile1(t *testing.T) {
 		})
 	}
 }
+
+// Test_openProcessingFileStdin is not parallel because it replaces os.Stdin.
+func Test_openProcessingFileStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		_ = r.Close()
+	})
+	os.Stdin = r
+
+	go func() {
+		_, _ = w.WriteString("1,5,0,2\n2,9,3,1\n")
+		_ = w.Close()
+	}()
+
+	f, closeFn, err := openProcessingFile("binary_name", "-")
+	if err != nil {
+		t.Fatalf("openProcessingFile() error = %v", err)
+	}
+	closeFn()
+	if f != r {
+		t.Fatalf("openProcessingFile() = %v, want stdin", f)
+	}
+
+	got, err := loadProcesses(f)
+	if err != nil {
+		t.Fatalf("loadProcesses() error = %v", err)
+	}
+	want := []Process{
+		{ProcessID: 1, ArrivalTime: 0, BurstDuration: 5, Priority: 2},
+		{ProcessID: 2, ArrivalTime: 3, BurstDuration: 9, Priority: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadProcesses() = %v, want %v", got, want)
+	}
+}
+
+// Test_runOut is not parallel because run sets the package's scheduling conventions.
+func Test_runOut(t *testing.T) {
+	out := path.Join(t.TempDir(), "results.txt")
+	if err := os.WriteFile(out, []byte("stale output"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run("binary_name", "-out", out, "example_processes.csv"); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(got), "stale output") {
+		t.Errorf("run() did not truncate the output file")
+	}
+	for _, title := range []string{"First-come, first-serve", "Shortest-job-first", "Priority", "Round-robin", "Summary"} {
+		if !strings.Contains(string(got), title) {
+			t.Errorf("run() output is missing %q", title)
+		}
+	}
+}
+
+// Test_runCheck is not parallel because run sets the package's scheduling conventions.
+func Test_runCheck(t *testing.T) {
+	dir := t.TempDir()
+	invalid := path.Join(dir, "invalid.csv")
+	if err := os.WriteFile(invalid, []byte("1,5,0,2\n1,9,3,1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		file    string
+		wantOut string
+		wantErr error
+	}{
+		{
+			name:    "valid",
+			file:    "example_processes.csv",
+			wantOut: "3 processes, total burst 20, arriving from 0 to 6\n",
+		},
+		{
+			name:    "invalid",
+			file:    invalid,
+			wantErr: ErrInvalidRow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := path.Join(dir, tt.name+".txt")
+			err := run("binary_name", "-check", "-out", out, tt.file)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("run() error = %v, want %v", err, tt.wantErr)
+			}
+			got, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.wantOut {
+				t.Errorf("run() = %q, want %q", got, tt.wantOut)
+			}
+		})
+	}
+}
+
+func Test_openOutputFile(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"", "-"} {
+		f, closeFn, err := openOutputFile(name)
+		if err != nil {
+			t.Fatalf("openOutputFile(%q) error = %v", name, err)
+		}
+		if f != os.Stdout {
+			t.Errorf("openOutputFile(%q) = %v, want stdout", name, f)
+		}
+		if err := closeFn(); err != nil {
+			t.Errorf("openOutputFile(%q) close error = %v", name, err)
+		}
+	}
+
+	if _, _, err := openOutputFile(path.Join(t.TempDir(), "missing", "results.txt")); err == nil {
+		t.Errorf("openOutputFile() in a missing directory error = nil, want an error")
+	}
+}
+
+func Test_parseFlags(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     []string
+		wantOpts options
+		wantArgs []string
+		wantErr  error
+	}{
+		{
+			name:     "defaults",
+			args:     []string{"binary_name", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "quantum",
+			args:     []string{"binary_name", "-quantum", "2", "processes.csv"},
+			wantOpts: options{format: "table", quantum: 2, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "mlfq",
+			args:     []string{"binary_name", "-mlfq-quanta", "1,3", "-mlfq-aging", "10", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{1, 3}, mlfqAging: 10, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "bad mlfq quanta",
+			args:    []string{"binary_name", "-mlfq-quanta", "1,0", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "aging",
+			args:     []string{"binary_name", "-aging", "3", "processes.csv"},
+			wantOpts: options{format: "table", aging: 3, quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "negative aging",
+			args:    []string{"binary_name", "-aging=-1", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "negative mlfq aging",
+			args:    []string{"binary_name", "-mlfq-aging=-1", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "json format",
+			args:     []string{"binary_name", "-format", "json", "processes.csv"},
+			wantOpts: options{format: "json", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "switch cost",
+			args:     []string{"binary_name", "-switch-cost", "2", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, switchCost: 2, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "negative switch cost",
+			args:    []string{"binary_name", "-switch-cost=-1", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "pid tie-break",
+			args:     []string{"binary_name", "-tie-break", "pid", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "pid", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "unknown tie-break",
+			args:    []string{"binary_name", "-tie-break", "random", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "cores",
+			args:     []string{"binary_name", "-cores", "2", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 2, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "zero cores",
+			args:    []string{"binary_name", "-cores", "0", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "generate",
+			args:     []string{"binary_name", "-generate", "50", "-seed", "7", "-dump"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", generate: 50, seed: 7, maxBurst: 10, maxArrival: 20, maxPriority: 5, dump: true},
+			wantArgs: []string{"binary_name"},
+		},
+		{
+			name:    "generate with zero max burst",
+			args:    []string{"binary_name", "-generate", "50", "-max-burst", "0"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "dump without generate",
+			args:    []string{"binary_name", "-dump", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "markdown format",
+			args:     []string{"binary_name", "-format", "markdown", "processes.csv"},
+			wantOpts: options{format: "markdown", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "high priority order",
+			args:     []string{"binary_name", "-priority-order", "high", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "high", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "unknown priority order",
+			args:    []string{"binary_name", "-priority-order", "middle", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "out",
+			args:     []string{"binary_name", "-out", "results.txt", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, out: "results.txt"},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "color",
+			args:     []string{"binary_name", "-color", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, color: true},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "verbose",
+			args:     []string{"binary_name", "-verbose", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, verbose: true},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "detailed",
+			args:     []string{"binary_name", "-detailed", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, detailed: true},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "fractional times",
+			args:     []string{"binary_name", "-decimals", "1", "-quantum", "0.5", "-switch-cost", ".2", "processes.csv"},
+			wantOpts: options{format: "table", decimals: 1, quantum: 5, mlfqQuanta: []int64{20, 40, 80}, switchCost: 2, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "fractional quantum without decimals",
+			args:    []string{"binary_name", "-quantum", "0.5", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "too many decimals",
+			args:    []string{"binary_name", "-decimals", "10", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "starvation threshold",
+			args:     []string{"binary_name", "-starvation-threshold", "10", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, starvationThreshold: 10},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "negative starvation threshold",
+			args:    []string{"binary_name", "-starvation-threshold=-1", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "check",
+			args:     []string{"binary_name", "-check", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, check: true},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "preemptive",
+			args:     []string{"binary_name", "-preemptive", "-algo", "sjf", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, algos: []string{"sjf"}, preemptive: true},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "csv format",
+			args:     []string{"binary_name", "-format", "csv", "processes.csv"},
+			wantOpts: options{format: "csv", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "new arrivals first",
+			args:     []string{"binary_name", "-new-arrivals-first", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, newArrivalsFirst: true},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "unknown format",
+			args:    []string{"binary_name", "-format", "xml", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "zero quantum",
+			args:    []string{"binary_name", "-quantum", "0", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "negative quantum",
+			args:    []string{"binary_name", "-quantum=-3", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"binary_name", "-bogus", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:     "step rr",
+			args:     []string{"binary_name", "-algo", "rr", "-step", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, algos: []string{"rr"}, step: true},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:     "algo list",
+			args:     []string{"binary_name", "-algo", "rr, fcfs", "processes.csv"},
+			wantOpts: options{format: "table", quantum: defaultQuantum, mlfqQuanta: []int64{2, 4, 8}, tieBreak: "arrival", cores: 1, priorityOrder: "low", seed: 1, maxBurst: 10, maxArrival: 20, maxPriority: 5, algos: []string{"rr", "fcfs"}},
+			wantArgs: []string{"binary_name", "processes.csv"},
+		},
+		{
+			name:    "step with several algos",
+			args:    []string{"binary_name", "-algo", "fcfs,rr", "-step", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "unknown algo",
+			args:    []string{"binary_name", "-algo", "hrrn", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "step without algo",
+			args:    []string{"binary_name", "-step", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "step unsupported algo",
+			args:    []string{"binary_name", "-algo", "mlfq", "-step", "processes.csv"},
+			wantErr: ErrInvalidArgs,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			gotOpts, gotArgs, err := parseFlags(tt.args...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(gotOpts, tt.wantOpts) {
+				t.Errorf("parseFlags() opts = %v, want %v", gotOpts, tt.wantOpts)
+			}
+			if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("parseFlags() args = %v, want %v", gotArgs, tt.wantArgs)
+			}
+		})
+	}
+}
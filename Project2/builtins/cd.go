@@ -4,23 +4,54 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 var (
 	ErrInvalidArgCount = errors.New("invalid argument count")
 	HomeDir, _         = os.UserHomeDir()
+	// PreviousDir is the working directory before the last successful ChangeDirectory, which "cd -" returns to.
+	PreviousDir string
 )
 
+// ChangeDirectory changes the working directory, setting OLDPWD to the one it left and PWD to the new absolute path.
 func ChangeDirectory(args ...string) error {
+	var dir string
 	switch len(args) {
-	case 0: // change to home directory if available
-		if HomeDir == "" {
+	case 0: // change to $HOME, as ~ expands to, or else the home directory found at startup
+		if dir = os.Getenv("HOME"); dir == "" {
+			dir = HomeDir
+		}
+		if dir == "" {
 			return fmt.Errorf("%w: no homedir found, expected one argument (directory)", ErrInvalidArgCount)
 		}
-		return os.Chdir(HomeDir)
 	case 1:
-		return os.Chdir(args[0])
+		dir = args[0]
+		if dir == "-" {
+			if PreviousDir == "" {
+				return fmt.Errorf("%w: no previous directory", ErrInvalidArgCount)
+			}
+			dir = PreviousDir
+		}
 	default:
 		return fmt.Errorf("%w: expected zero or one arguments (directory)", ErrInvalidArgCount)
 	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	// resolved before the Chdir, which would change what a relative dir is relative to.
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	PreviousDir = wd
+	if err := os.Setenv("OLDPWD", wd); err != nil {
+		return err
+	}
+	return os.Setenv("PWD", abs)
 }
@@ -2,7 +2,9 @@ package builtins_test
 
 import (
 	"errors"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/vinhtrinh326/CSCE4600/Project2/builtins"
@@ -10,6 +12,7 @@ import (
 
 func TestChangeDirectory(t *testing.T) {
 	tmp := t.TempDir()
+	previous := t.TempDir()
 
 	type args struct {
 		args []string
@@ -18,6 +21,8 @@ func TestChangeDirectory(t *testing.T) {
 		name         string
 		args         args
 		unsetHomedir bool
+		home         string
+		previousDir  string
 		wantDir      string
 		wantErr      error
 	}{
@@ -32,6 +37,11 @@ func TestChangeDirectory(t *testing.T) {
 			name:    "no args should change to homedir if available",
 			wantDir: builtins.HomeDir,
 		},
+		{
+			name:    "no args should prefer $HOME to homedir",
+			home:    tmp,
+			wantDir: tmp,
+		},
 		{
 			name:         "no args should error if homedir is blank",
 			unsetHomedir: true,
@@ -44,6 +54,28 @@ func TestChangeDirectory(t *testing.T) {
 			},
 			wantDir: tmp,
 		},
+		{
+			name: "dash should change to the previous dir",
+			args: args{
+				args: []string{"-"},
+			},
+			previousDir: previous,
+			wantDir:     previous,
+		},
+		{
+			name: "dash should error without a previous dir",
+			args: args{
+				args: []string{"-"},
+			},
+			wantErr: builtins.ErrInvalidArgCount,
+		},
+		{
+			name: "missing dir should error",
+			args: args{
+				args: []string{filepath.Join(tmp, "missing")},
+			},
+			wantErr: fs.ErrNotExist,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -54,7 +86,23 @@ func TestChangeDirectory(t *testing.T) {
 					builtins.HomeDir = oldVal
 				})
 				builtins.HomeDir = ""
+				t.Setenv("HOME", "")
+			}
+			if tt.home != "" {
+				t.Setenv("HOME", tt.home)
 			}
+			oldPrevious := builtins.PreviousDir
+			t.Cleanup(func() {
+				builtins.PreviousDir = oldPrevious
+			})
+			builtins.PreviousDir = tt.previousDir
+			before, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("Could not get working dir")
+			}
+			t.Cleanup(func() {
+				_ = os.Chdir(before)
+			})
 
 			// testing
 			if err := builtins.ChangeDirectory(tt.args.args...); tt.wantErr != nil {
@@ -82,6 +130,39 @@ func TestChangeDirectory(t *testing.T) {
 			if !os.SameFile(d1, d2) {
 				t.Errorf("Working Directory = %v, wantDir %v", wd, tt.wantDir)
 			}
+			if builtins.PreviousDir != before {
+				t.Errorf("PreviousDir = %v, want %v", builtins.PreviousDir, before)
+			}
 		})
 	}
 }
+
+func TestChangeDirectorySetsPWD(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+	before, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Could not get working dir")
+	}
+	oldPrevious := builtins.PreviousDir
+	t.Cleanup(func() {
+		builtins.PreviousDir = oldPrevious
+		_ = os.Chdir(before)
+	})
+	t.Setenv("PWD", before)
+	t.Setenv("OLDPWD", "")
+
+	if err := builtins.ChangeDirectory(first); err != nil {
+		t.Fatalf("ChangeDirectory() unexpected error: %v", err)
+	}
+	// relative to first, so PWD must still come out absolute.
+	if err := builtins.ChangeDirectory(filepath.Join("..", filepath.Base(second))); err != nil {
+		t.Fatalf("ChangeDirectory() unexpected error: %v", err)
+	}
+	if got := os.Getenv("PWD"); got != second {
+		t.Errorf("PWD = %v, want %v", got, second)
+	}
+	if got := os.Getenv("OLDPWD"); got != first {
+		t.Errorf("OLDPWD = %v, want %v", got, first)
+	}
+}
@@ -0,0 +1,35 @@
+package builtins
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var ErrInvalidAssignment = errors.New("invalid assignment")
+
+// ExportVariable sets each KEY=VALUE argument in the environment, so the commands the shell runs inherit it.
+// A bare KEY must name a variable that is already set, which is then left as it is.
+func ExportVariable(args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: expected at least one argument (KEY=VALUE)", ErrInvalidArgCount)
+	}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if key == "" {
+			return fmt.Errorf("%w: %q has no name", ErrInvalidAssignment, arg)
+		}
+		if !ok {
+			if _, set := os.LookupEnv(key); !set {
+				return fmt.Errorf("%w: %s is not set, expected KEY=VALUE", ErrInvalidAssignment, key)
+			}
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAssignment, err)
+		}
+	}
+
+	return nil
+}
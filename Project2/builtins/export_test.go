@@ -0,0 +1,83 @@
+package builtins_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/vinhtrinh326/CSCE4600/Project2/builtins"
+)
+
+func TestExportVariable(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		setEnv  map[string]string
+		wantEnv map[string]string
+		wantErr error
+	}{
+		{
+			name:    "error no args",
+			wantErr: builtins.ErrInvalidArgCount,
+		},
+		{
+			name:    "one assignment",
+			args:    []string{"GOSH_EXPORT_A=1"},
+			wantEnv: map[string]string{"GOSH_EXPORT_A": "1"},
+		},
+		{
+			name:    "several assignments",
+			args:    []string{"GOSH_EXPORT_A=1", "GOSH_EXPORT_B=two words", "GOSH_EXPORT_C="},
+			wantEnv: map[string]string{"GOSH_EXPORT_A": "1", "GOSH_EXPORT_B": "two words", "GOSH_EXPORT_C": ""},
+		},
+		{
+			name:    "value containing =",
+			args:    []string{"GOSH_EXPORT_A=b=c"},
+			wantEnv: map[string]string{"GOSH_EXPORT_A": "b=c"},
+		},
+		{
+			name:    "existing variable",
+			args:    []string{"GOSH_EXPORT_A"},
+			setEnv:  map[string]string{"GOSH_EXPORT_A": "kept"},
+			wantEnv: map[string]string{"GOSH_EXPORT_A": "kept"},
+		},
+		{
+			name:    "error unset variable without value",
+			args:    []string{"GOSH_EXPORT_A"},
+			wantErr: builtins.ErrInvalidAssignment,
+		},
+		{
+			name:    "error no name",
+			args:    []string{"=1"},
+			wantErr: builtins.ErrInvalidAssignment,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup: restore the environment afterwards.
+			for _, k := range []string{"GOSH_EXPORT_A", "GOSH_EXPORT_B", "GOSH_EXPORT_C"} {
+				t.Setenv(k, "")
+				_ = os.Unsetenv(k)
+			}
+			for k, v := range tt.setEnv {
+				t.Setenv(k, v)
+			}
+
+			// testing
+			if err := builtins.ExportVariable(tt.args...); tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ExportVariable() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("ExportVariable() unexpected error: %v", err)
+			}
+
+			for k, want := range tt.wantEnv {
+				if got, ok := os.LookupEnv(k); !ok || got != want {
+					t.Errorf("os.LookupEnv(%q) = %q, %v, want %q", k, got, ok, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package builtins
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotInPath = errors.New("not in PATH")
+
+// Path prints each PATH entry on its own line, or edits PATH:
+// "add DIR" appends DIR, "prepend DIR" puts it first, and "remove DIR" deletes it.
+// Editing also drops the entries that repeat an earlier one, so DIR ends up in PATH once.
+func Path(w io.Writer, args ...string) error {
+	entries := filepath.SplitList(os.Getenv("PATH"))
+	if len(args) == 0 {
+		for _, entry := range entries {
+			if _, err := fmt.Fprintln(w, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("%w: expected add, prepend, or remove and a directory", ErrInvalidArgCount)
+	}
+
+	dir := args[1]
+	switch args[0] {
+	case "add":
+		entries = append(entries, dir)
+	case "prepend":
+		entries = append([]string{dir}, entries...)
+	case "remove":
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry != dir {
+				kept = append(kept, entry)
+			}
+		}
+		if len(kept) == len(entries) {
+			return fmt.Errorf("%w: %s", ErrNotInPath, dir)
+		}
+		entries = kept
+	default:
+		return fmt.Errorf("%w: unknown subcommand %s, expected add, prepend, or remove", ErrInvalidArgCount, args[0])
+	}
+
+	seen := make(map[string]bool, len(entries))
+	unique := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !seen[entry] {
+			seen[entry] = true
+			unique = append(unique, entry)
+		}
+	}
+	return os.Setenv("PATH", strings.Join(unique, string(filepath.ListSeparator)))
+}
@@ -0,0 +1,108 @@
+package builtins_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/vinhtrinh326/CSCE4600/Project2/builtins"
+)
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		args     []string
+		wantPath string
+		wantErr  error
+	}{
+		{
+			name:     "add",
+			path:     "/bin:/usr/bin",
+			args:     []string{"add", "/opt/bin"},
+			wantPath: "/bin:/usr/bin:/opt/bin",
+		},
+		{
+			name:     "prepend",
+			path:     "/bin:/usr/bin",
+			args:     []string{"prepend", "/opt/bin"},
+			wantPath: "/opt/bin:/bin:/usr/bin",
+		},
+		{
+			name:     "remove",
+			path:     "/bin:/opt/bin:/usr/bin:/opt/bin",
+			args:     []string{"remove", "/opt/bin"},
+			wantPath: "/bin:/usr/bin",
+		},
+		{
+			name:     "add an entry already there",
+			path:     "/bin:/usr/bin",
+			args:     []string{"add", "/bin"},
+			wantPath: "/bin:/usr/bin",
+		},
+		{
+			name:     "prepend an entry already there moves it first",
+			path:     "/bin:/usr/bin",
+			args:     []string{"prepend", "/usr/bin"},
+			wantPath: "/usr/bin:/bin",
+		},
+		{
+			name:     "editing drops duplicates",
+			path:     "/bin:/usr/bin:/bin",
+			args:     []string{"add", "/opt/bin"},
+			wantPath: "/bin:/usr/bin:/opt/bin",
+		},
+		{
+			name:     "error removing a missing entry",
+			path:     "/bin",
+			args:     []string{"remove", "/opt/bin"},
+			wantPath: "/bin",
+			wantErr:  builtins.ErrNotInPath,
+		},
+		{
+			name:     "error unknown subcommand",
+			path:     "/bin",
+			args:     []string{"insert", "/opt/bin"},
+			wantPath: "/bin",
+			wantErr:  builtins.ErrInvalidArgCount,
+		},
+		{
+			name:     "error missing dir",
+			path:     "/bin",
+			args:     []string{"add"},
+			wantPath: "/bin",
+			wantErr:  builtins.ErrInvalidArgCount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			t.Setenv("PATH", tt.path)
+
+			// testing
+			if err := builtins.Path(nil, tt.args...); tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Path() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Path() unexpected error: %v", err)
+			}
+
+			if got := os.Getenv("PATH"); got != tt.wantPath {
+				t.Errorf("PATH = %q, want %q", got, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestPathPrints(t *testing.T) {
+	t.Setenv("PATH", "/bin:/usr/bin")
+	w := &bytes.Buffer{}
+	if err := builtins.Path(w); err != nil {
+		t.Fatalf("Path() unexpected error: %v", err)
+	}
+	if got, want := w.String(), "/bin\n/usr/bin\n"; got != want {
+		t.Errorf("Path() printed %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package builtins
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var ErrInvalidMode = errors.New("invalid mode")
+
+// Umask prints the file mode creation mask in octal, or with an argument, sets it to that octal mode.
+func Umask(w io.Writer, args ...string) error {
+	switch len(args) {
+	case 0:
+		// the mask can only be read by setting it, so put it straight back.
+		mask, err := umask(0)
+		if err != nil {
+			return err
+		}
+		if _, err := umask(mask); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%04o\n", mask)
+		return err
+	case 1:
+		mask, err := strconv.ParseUint(args[0], 8, 32)
+		if err != nil || mask > 0o777 {
+			return fmt.Errorf("%w: %s is not an octal mode up to 0777", ErrInvalidMode, args[0])
+		}
+		_, err = umask(int(mask))
+		return err
+	default:
+		return fmt.Errorf("%w: expected zero or one arguments (mode)", ErrInvalidArgCount)
+	}
+}
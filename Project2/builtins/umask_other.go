@@ -0,0 +1,12 @@
+//go:build !unix
+
+package builtins
+
+import "errors"
+
+var errUmaskUnsupported = errors.New("umask: not supported on this system")
+
+// umask fails, as there is no file mode creation mask outside of Unix.
+func umask(int) (int, error) {
+	return 0, errUmaskUnsupported
+}
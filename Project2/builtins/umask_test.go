@@ -0,0 +1,96 @@
+//go:build unix
+
+package builtins_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/vinhtrinh326/CSCE4600/Project2/builtins"
+)
+
+func TestUmask(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "set",
+			args: []string{"027"},
+			want: "0027\n",
+		},
+		{
+			name: "set without leading zero",
+			args: []string{"7"},
+			want: "0007\n",
+		},
+		{
+			name:    "error not octal",
+			args:    []string{"089"},
+			wantErr: builtins.ErrInvalidMode,
+		},
+		{
+			name:    "error too large",
+			args:    []string{"1000"},
+			wantErr: builtins.ErrInvalidMode,
+		},
+		{
+			name:    "error too many args",
+			args:    []string{"022", "077"},
+			wantErr: builtins.ErrInvalidArgCount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			old := syscall.Umask(0o22)
+			t.Cleanup(func() { syscall.Umask(old) })
+
+			// testing
+			if err := builtins.Umask(nil, tt.args...); tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Umask() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("Umask() unexpected error: %v", err)
+			}
+
+			w := &bytes.Buffer{}
+			if err := builtins.Umask(w); err != nil {
+				t.Fatalf("Umask() unexpected error: %v", err)
+			}
+			if got := w.String(); got != tt.want {
+				t.Errorf("Umask() printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUmaskAppliesToNewFiles(t *testing.T) {
+	old := syscall.Umask(0o22)
+	t.Cleanup(func() { syscall.Umask(old) })
+
+	if err := builtins.Umask(nil, "077"); err != nil {
+		t.Fatalf("Umask() unexpected error: %v", err)
+	}
+	name := filepath.Join(t.TempDir(), "file")
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != 0o600 {
+		t.Errorf("file mode = %o, want %o", got, 0o600)
+	}
+}
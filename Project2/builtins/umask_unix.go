@@ -0,0 +1,10 @@
+//go:build unix
+
+package builtins
+
+import "syscall"
+
+// umask sets the file mode creation mask to mask and returns the previous one.
+func umask(mask int) (int, error) {
+	return syscall.Umask(mask), nil
+}
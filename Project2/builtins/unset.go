@@ -0,0 +1,21 @@
+package builtins
+
+import (
+	"fmt"
+	"os"
+)
+
+// UnsetVariable removes each named variable from the environment.
+// Names that are not set are ignored.
+func UnsetVariable(args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: expected at least one argument (name)", ErrInvalidArgCount)
+	}
+	for _, name := range args {
+		if err := os.Unsetenv(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package builtins_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/vinhtrinh326/CSCE4600/Project2/builtins"
+)
+
+func TestUnsetVariable(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		setEnv    map[string]string
+		wantUnset []string
+		wantSet   []string
+		wantErr   error
+	}{
+		{
+			name:    "error no args",
+			wantErr: builtins.ErrInvalidArgCount,
+		},
+		{
+			name:      "one name",
+			args:      []string{"GOSH_UNSET_A"},
+			setEnv:    map[string]string{"GOSH_UNSET_A": "1", "GOSH_UNSET_B": "2"},
+			wantUnset: []string{"GOSH_UNSET_A"},
+			wantSet:   []string{"GOSH_UNSET_B"},
+		},
+		{
+			name:      "several names",
+			args:      []string{"GOSH_UNSET_A", "GOSH_UNSET_B"},
+			setEnv:    map[string]string{"GOSH_UNSET_A": "1", "GOSH_UNSET_B": "2"},
+			wantUnset: []string{"GOSH_UNSET_A", "GOSH_UNSET_B"},
+		},
+		{
+			name:      "name not set is ignored",
+			args:      []string{"GOSH_UNSET_MISSING", "GOSH_UNSET_A"},
+			setEnv:    map[string]string{"GOSH_UNSET_A": "1"},
+			wantUnset: []string{"GOSH_UNSET_MISSING", "GOSH_UNSET_A"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// setup
+			for k, v := range tt.setEnv {
+				t.Setenv(k, v)
+			}
+
+			// testing
+			if err := builtins.UnsetVariable(tt.args...); tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("UnsetVariable() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("UnsetVariable() unexpected error: %v", err)
+			}
+
+			for _, k := range tt.wantUnset {
+				if v, ok := os.LookupEnv(k); ok {
+					t.Errorf("os.LookupEnv(%q) = %q, want it unset", k, v)
+				}
+			}
+			for _, k := range tt.wantSet {
+				if _, ok := os.LookupEnv(k); !ok {
+					t.Errorf("os.LookupEnv(%q) is unset, want it set", k)
+				}
+			}
+		})
+	}
+}
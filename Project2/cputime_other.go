@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// childCPUTime is only implemented for Unix; elsewhere time reports no CPU time.
+func childCPUTime() (user, sys time.Duration) {
+	return 0, 0
+}
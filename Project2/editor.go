@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// errInterrupted is returned by the line editor when Ctrl-C abandons the line being typed.
+var errInterrupted = errors.New("interrupted")
+
+// keys the line editor handles, as read from a terminal in raw mode.
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlH     = 8
+	keyTab       = '\t'
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyBackspace = 127
+)
+
+// lineEditor reads lines typed at a terminal a key at a time, so that Tab can complete the word being typed
+// and Ctrl-R can search the history. Only the end of the line can be edited.
+type lineEditor struct {
+	in  *bufio.Reader
+	out io.Writer
+	// raw puts the terminal in raw mode for the length of a readLine, returning how to restore it.
+	raw func() (restore func(), err error)
+	// complete returns the sorted words the last word of a line could be completed to.
+	complete func(line string) []string
+	// history returns the lines entered so far, oldest first.
+	history func() []string
+}
+
+// newLineEditor returns a line editor for the terminal f that echoes to w, completes words with complete,
+// and searches the lines returned by history.
+func newLineEditor(f *os.File, w io.Writer, complete func(line string) []string, history func() []string) *lineEditor {
+	return &lineEditor{
+		in:       bufio.NewReader(f),
+		out:      w,
+		raw:      func() (func(), error) { return makeRaw(f) },
+		complete: complete,
+		history:  history,
+	}
+}
+
+// readLine writes prompt and reads a line, newline included. Ctrl-D on an empty line returns io.EOF,
+// and Ctrl-C abandons the line with errInterrupted. Without raw mode the line is read as typed, without editing.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	_, _ = io.WriteString(e.out, prompt)
+	restore, err := e.raw()
+	if err != nil {
+		return e.in.ReadString('\n')
+	}
+	defer restore()
+
+	var line string
+	for {
+		b, err := e.in.ReadByte()
+		if err != nil {
+			return line, err
+		}
+		switch b {
+		case '\r', '\n':
+			_, _ = io.WriteString(e.out, "\n")
+			return line + "\n", nil
+		case keyCtrlD:
+			if line == "" {
+				return "", io.EOF
+			}
+		case keyCtrlC:
+			_, _ = io.WriteString(e.out, "^C\n")
+			return "", errInterrupted
+		case keyBackspace, keyCtrlH:
+			if line != "" {
+				_, size := utf8.DecodeLastRuneInString(line)
+				line = line[:len(line)-size]
+				_, _ = io.WriteString(e.out, "\b \b")
+			}
+		case keyCtrlU:
+			_, _ = io.WriteString(e.out, strings.Repeat("\b \b", utf8.RuneCountInString(line)))
+			line = ""
+		case keyTab:
+			line = e.completeLine(prompt, line)
+		case keyCtrlR:
+			match, accepted, err := e.search(prompt)
+			if err != nil {
+				return "", err
+			}
+			if accepted {
+				return match + "\n", nil
+			}
+			line = ""
+		case keyEscape:
+			e.skipEscape()
+		default:
+			// control characters other than the keys above are ignored; bytes of UTF-8 runes are kept.
+			if b >= ' ' {
+				line += string([]byte{b})
+				_, _ = e.out.Write([]byte{b})
+			}
+		}
+	}
+}
+
+// skipEscape skips the rest of an escape sequence, such as an arrow key sends, after its escape.
+// A sequence arrives in a single read, so an escape with nothing buffered after it was the Escape key alone.
+func (e *lineEditor) skipEscape() {
+	if e.in.Buffered() == 0 {
+		return
+	}
+	if b, err := e.in.ReadByte(); err != nil || (b != '[' && b != 'O') {
+		return
+	}
+	for e.in.Buffered() > 0 {
+		// the sequence ends at its first byte in the range @ to ~.
+		if b, err := e.in.ReadByte(); err != nil || (b >= '@' && b <= '~') {
+			return
+		}
+	}
+}
+
+// search reads the query of an incremental reverse search of the history, showing the latest line containing it.
+// Ctrl-R again moves on to an older match. Enter accepts the match, or an empty line if there is none,
+// and Escape cancels the search back to the prompt with an empty line, returning false.
+func (e *lineEditor) search(prompt string) (string, bool, error) {
+	history := e.history()
+	var query string
+	match := -1
+	show := func() {
+		var line string
+		if match >= 0 {
+			line = history[match]
+		}
+		// return to the start of the terminal line and clear it before redrawing it.
+		_, _ = fmt.Fprintf(e.out, "\r\x1b[K(reverse-i-search)`%s': %s", query, line)
+	}
+	show()
+
+	for {
+		b, err := e.in.ReadByte()
+		if err != nil {
+			return "", false, err
+		}
+		switch b {
+		case '\r', '\n':
+			var line string
+			if match >= 0 {
+				line = history[match]
+			}
+			_, _ = fmt.Fprintf(e.out, "\r\x1b[K%s%s\n", prompt, line)
+			return line, true, nil
+		case keyCtrlC:
+			_, _ = io.WriteString(e.out, "^C\n")
+			return "", false, errInterrupted
+		case keyCtrlR:
+			if match > 0 {
+				if older := searchHistory(history[:match], query); older >= 0 {
+					match = older
+				}
+			}
+		case keyBackspace, keyCtrlH:
+			if query != "" {
+				_, size := utf8.DecodeLastRuneInString(query)
+				query = query[:len(query)-size]
+				match = searchHistory(history, query)
+			}
+		case keyEscape:
+			if e.in.Buffered() > 0 {
+				e.skipEscape()
+				break
+			}
+			_, _ = fmt.Fprintf(e.out, "\r\x1b[K%s", prompt)
+			return "", false, nil
+		default:
+			if b >= ' ' {
+				query += string([]byte{b})
+				match = searchHistory(history, query)
+			}
+		}
+		show()
+	}
+}
+
+// searchHistory returns the index of the latest line in history containing query, or -1 if none does or it's empty.
+func searchHistory(history []string, query string) int {
+	if query == "" {
+		return -1
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if strings.Contains(history[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// completeLine completes the last word of line as far as its candidates agree, returning the new line.
+// A single candidate is completed along with the space after it, unless it's a directory.
+// Candidates that can't be told apart yet are listed, followed by the prompt and line again.
+func (e *lineEditor) completeLine(prompt, line string) string {
+	word := line[lastWordStart(line):]
+	candidates := e.complete(line)
+	if len(candidates) == 0 {
+		_, _ = io.WriteString(e.out, "\a")
+		return line
+	}
+
+	completion := commonPrefix(candidates)
+	if len(candidates) == 1 && !strings.HasSuffix(completion, "/") {
+		completion += " "
+	}
+	if len(completion) > len(word) {
+		_, _ = io.WriteString(e.out, completion[len(word):])
+		return line + completion[len(word):]
+	}
+
+	names := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		names[i] = candidate[strings.LastIndex(strings.TrimSuffix(candidate, "/"), "/")+1:]
+	}
+	_, _ = fmt.Fprintf(e.out, "\n%s\n%s%s", strings.Join(names, "  "), prompt, line)
+	return line
+}
+
+// commonPrefix returns the longest prefix shared by every word, cut between characters rather than inside one.
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// lastWordStart returns the index in line of the word being typed, which follows the last space or operator.
+func lastWordStart(line string) int {
+	return strings.LastIndexAny(line, " \t|&;<>") + 1
+}
+
+// completions returns the sorted words the last word of line could be completed to.
+// At the start of a command, a word without a / completes to the builtins, aliases, and commands in $PATH.
+// Anywhere else it completes to the files it names the start of, with a / after directories.
+func (s *shell) completions(line string) []string {
+	start := lastWordStart(line)
+	word := line[start:]
+	before := strings.TrimRight(line[:start], " \t")
+	if (before == "" || strings.ContainsAny(before[len(before)-1:], "|&;")) && !strings.Contains(word, "/") {
+		return s.commandCompletions(word)
+	}
+	return fileCompletions(word)
+}
+
+// commandCompletions returns the sorted builtins, aliases, and executables in $PATH starting with prefix.
+func (s *shell) commandCompletions(prefix string) []string {
+	found := make(map[string]bool)
+	for name := range builtinCommands {
+		found[name] = true
+	}
+	for name := range s.aliases {
+		found[name] = true
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // a missing directory in $PATH isn't an error.
+		}
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+				found[entry.Name()] = true
+			}
+		}
+	}
+
+	var names []string
+	for name := range found {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fileCompletions returns the sorted paths of the files starting with word, which is relative to the working
+// directory unless absolute. Hidden files are only included once word's last element starts with a dot.
+func fileCompletions(word string) []string {
+	dir, base := filepath.Split(word)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		// Stat follows symlinks, so a link to a directory is completed as one.
+		if info, err := os.Stat(filepath.Join(readDir, name)); err == nil && info.IsDir() {
+			name += "/"
+		}
+		paths = append(paths, dir+name)
+	}
+	return paths
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// inTerminalForeground reports whether the process pid is in the foreground process group of the terminal on stdin,
+// which the terminal sends the interrupt of a Ctrl-C to itself.
+func inTerminalForeground(pid int) bool {
+	pgid, err := syscall.Getpgid(pid)
+	if err != nil {
+		return false
+	}
+	var foreground int32
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), syscall.TIOCGPGRP, uintptr(unsafe.Pointer(&foreground))); errno != 0 {
+		return false
+	}
+	return int(foreground) == pgid
+}
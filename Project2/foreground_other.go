@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+// inTerminalForeground is only implemented where the terminal's process group can be asked for;
+// elsewhere the shell sends every interrupt on itself.
+func inTerminalForeground(int) bool {
+	return false
+}
@@ -2,138 +2,2434 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"os/signal"
 	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vinhtrinh326/CSCE4600/Project2/builtins"
 )
 
 func main() {
+	norc := flag.Bool("norc", false, "do not run "+rcFileName+" on startup")
+	interactive := flag.Bool("interactive", true, "print prompts, which are left out anyway when the input isn't a terminal")
+	noclobber := flag.Bool("noclobber", false, "refuse to overwrite existing files with >, leaving >| to force it")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		os.Exit(runFile(flag.Arg(0), flag.Args()[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	sh := newShell(builtins.HomeDir, !*norc)
+	sh.noPrompt = !*interactive || !isTerminal(os.Stdin)
+	sh.options.noclobber = *noclobber
+	if !sh.noPrompt {
+		sh.editor = newLineEditor(os.Stdin, os.Stdout, sh.completions, func() []string { return sh.history })
+	}
+	exit := make(chan struct{}, 2) // buffer this so there's no deadlock.
+	os.Exit(sh.runLoop(os.Stdin, os.Stdout, os.Stderr, exit))
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+const (
+	// historyFileName is the file in the home directory that history is kept in between sessions.
+	historyFileName = ".gosh_history"
+	// rcFileName is the file in the home directory that is sourced on startup.
+	rcFileName = ".goshrc"
+)
+
+// newShell returns a shell that keeps its history in the home directory, and sources the startup file there if rc is set.
+// With an empty home, neither file is used.
+func newShell(home string, rc bool) *shell {
+	sh := &shell{}
+	if home != "" {
+		sh.historyFile = filepath.Join(home, historyFileName)
+		if rc {
+			sh.rcFile = filepath.Join(home, rcFileName)
+		}
+	}
+	return sh
+}
+
+// runFile runs the commands in the named file as the shell's input, with args as its positional parameters
+// and without prompts, history, or the startup file, leaving stdin for read to take its lines from,
+// returning the status to exit with: the last command's, or 127 if the file can't be opened.
+func runFile(name string, args []string, stdin io.Reader, w, errW io.Writer) int {
+	f, err := os.Open(name)
+	if err != nil {
+		_, _ = fmt.Fprintln(errW, err)
+		return 127
+	}
+	defer f.Close()
+
+	sh := newShell("", false)
+	sh.noPrompt = true
+	sh.params = args
+	sh.stdin = bufio.NewReader(stdin)
 	exit := make(chan struct{}, 2) // buffer this so there's no deadlock.
-	runLoop(os.Stdin, os.Stdout, os.Stderr, exit)
+	return sh.runLoop(f, w, errW, exit)
+}
+
+// shell holds the state that lasts between the commands of a session.
+type shell struct {
+	// history is every non-empty line entered, oldest first.
+	history []string
+	// historyFile is where history is loaded from on startup and saved to on exit, or empty to not keep it.
+	historyFile string
+	// rcFile is sourced on startup if it exists, or empty to not source a file.
+	rcFile string
+	// jobs are the commands started in the background that have not been reported done, oldest first.
+	jobs []*job
+	// status is the exit status of the last pipeline run, which $? expands to.
+	status int
+	// params are the arguments of the script being run, which $1 to $9 and $# expand to, or nil outside a script.
+	params []string
+	// dirStack are the directories pushd left, which popd returns to, with the latest last.
+	dirStack []string
+	// aliases are the words that stand for other text at the start of a command.
+	aliases map[string]string
+	// exit is where the input being handled asks the loop to exit.
+	exit chan<- struct{}
+	// exiting is set once an exit is asked for, which stops the rest of the list or sourced file from running.
+	exiting bool
+	// exitCode is the status the shell exits with once the loop exits.
+	exitCode int
+	// noPrompt turns off the prompts and the exit message, for input that isn't typed at a terminal.
+	noPrompt bool
+	// options are the settings that set -o turns on and set +o turns off.
+	options options
+	// input is what the loop reads lines from, which read takes its line from too unless stdin is set.
+	input *bufio.Reader
+	// stdin is what read takes its line from when the input is a script rather than what the shell was given,
+	// or nil to read from input.
+	stdin *bufio.Reader
+	// errW is where the loop reports errors, which builtins write their diagnostics to as well, or nil for os.Stderr.
+	errW io.Writer
+	// editor reads the lines typed at a terminal, or is nil to read the input as it comes.
+	editor *lineEditor
+
+	// mu guards running and reading, which are read when an interrupt arrives, and the prompts written while reading.
+	mu sync.Mutex
+	// running are the commands in the foreground, which are sent any interrupt the shell gets.
+	running []*exec.Cmd
+	// reading is set while the loop waits for a line of input as it comes, the only time an interrupt gives a fresh prompt.
+	reading bool
+}
+
+// options are the shell's on-or-off settings, by the names set knows them as in optionNames.
+type options struct {
+	// noclobber makes > refuse to overwrite an existing file, which >| still does.
+	noclobber bool
+	// xtrace prints each command to errW with a leading + once it is expanded, before it runs.
+	xtrace bool
+}
+
+// optionNames are the names of the options, in the order set -o lists them.
+var optionNames = []string{"noclobber", "xtrace"}
+
+// option returns the setting called name, or false if there is no such option.
+func (o *options) option(name string) (*bool, bool) {
+	switch name {
+	case "noclobber":
+		return &o.noclobber, true
+	case "xtrace":
+		return &o.xtrace, true
+	}
+	return nil, false
+}
+
+// stderr is where builtins write diagnostics: errW, or os.Stderr when the loop has not set it.
+func (s *shell) stderr() io.Writer {
+	if s.errW == nil {
+		return os.Stderr
+	}
+	return s.errW
+}
+
+// job is a command started in the background with a trailing &.
+type job struct {
+	id   int
+	line string
+	cmd  *exec.Cmd
+	// done is closed once the command has exited, after err is set.
+	done chan struct{}
+	err  error
+	// stopped is set while the shell has the command stopped.
+	stopped bool
+}
+
+// state describes whether j is running, stopped, or done, and how it exited.
+func (j *job) state() string {
+	select {
+	case <-j.done:
+		if j.err != nil {
+			return fmt.Sprintf("Exit (%v)", j.err)
+		}
+		return "Done"
+	default:
+		if j.stopped {
+			return "Stopped"
+		}
+		return "Running"
+	}
+}
+
+// finished reports whether the command of j has exited.
+func (j *job) finished() bool {
+	select {
+	case <-j.done:
+		return true
+	default:
+		return false
+	}
 }
 
-func runLoop(r io.Reader, w, errW io.Writer, exit chan struct{}) {
+// runLoop reads and runs lines from r until an exit, returning the status the shell exits with.
+func (s *shell) runLoop(r io.Reader, w, errW io.Writer, exit chan struct{}) int {
 	var (
 		input    string
 		err      error
 		readLoop = bufio.NewReader(r)
 	)
-	for {
+	s.input = readLoop
+	s.errW = errW
+	if err := s.loadHistory(); err != nil {
+		_, _ = fmt.Fprintln(errW, err)
+	}
+	if err := s.sourceRC(w, exit); err != nil {
+		_, _ = fmt.Fprintln(errW, err)
+	}
+
+	// an interrupt such as Ctrl-C stops the running command instead of the shell.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer func() {
+		signal.Stop(interrupts)
+		close(interrupts)
+	}()
+	go func() {
+		for range interrupts {
+			s.interrupt(w)
+		}
+	}()
+
+	for !s.exiting {
 		select {
 		case <-exit:
-			_, _ = fmt.Fprintln(w, "exiting gracefully...")
-			return
+			s.exiting = true
 		default:
-			if err := printPrompt(w); err != nil {
-				_, _ = fmt.Fprintln(errW, err)
-				continue
+			s.reportJobs(w)
+			prompt, continued := "", ""
+			if !s.noPrompt {
+				if prompt, err = currentPrompt(); err != nil {
+					_, _ = fmt.Fprintln(errW, err)
+					continue
+				}
+				continued = continuationPrompt
 			}
-			if input, err = readLoop.ReadString('\n'); err != nil {
+			input, err = s.readLine(readLoop, w, prompt)
+			for err == nil {
+				joined, more := continuation(input)
+				if !more {
+					break
+				}
+				var next string
+				next, err = s.readLine(readLoop, w, continued)
+				input = joined + next
+			}
+			if errors.Is(err, errInterrupted) {
+				continue // Ctrl-C abandoned the line for a fresh prompt.
+			}
+			eof := errors.Is(err, io.EOF)
+			if err != nil && !eof {
 				_, _ = fmt.Fprintln(errW, err)
 				continue
 			}
-			if err = handleInput(w, input, exit); err != nil {
+			// a last line without a newline is still run at the end of the input.
+			if line := strings.TrimSpace(input); line != "" {
+				s.history = append(s.history, line)
+			}
+			if err = unreported(s.handleInput(w, input, exit)); err != nil {
 				_, _ = fmt.Fprintln(errW, err)
 			}
+			if eof {
+				// the end of the input, such as Ctrl-D, exits on a line of its own with the last status.
+				if !s.noPrompt {
+					_, _ = fmt.Fprintln(w)
+				}
+				s.requestExit(s.status)
+			}
 		}
 	}
+
+	if err := s.saveHistory(); err != nil {
+		_, _ = fmt.Fprintln(errW, err)
+	}
+	if !s.noPrompt {
+		_, _ = fmt.Fprintln(w, "exiting gracefully...")
+	}
+	return s.exitCode
+}
+
+const (
+	// defaultPrompt is the prompt template used when PS1 is unset.
+	defaultPrompt = `\w [\u] $ `
+	// continuationPrompt is printed before each line that continues a command.
+	continuationPrompt = "> "
+)
+
+// continuation reports whether the command of line continues onto the next line, returning what the next line joins.
+// A line ending in an unquoted backslash continues without the backslash and newline,
+// and a line leaving a quote open continues with the quoted newline kept.
+func continuation(line string) (string, bool) {
+	text := strings.TrimSuffix(line, "\n")
+	tokens, err := tokenize(text)
+	if errors.Is(err, errUnterminated) {
+		return line, true
+	}
+	if err != nil || len(tokens) == 0 {
+		return line, false
+	}
+	// the backslash has to end the last word, rather than a comment after it.
+	last := tokens[len(tokens)-1]
+	if last.op || !strings.HasSuffix(text, last.text) {
+		return line, false
+	}
+	if backslashes := len(last.text) - len(strings.TrimRight(last.text, `\`)); backslashes%2 == 0 {
+		return line, false
+	}
+	return text[:len(text)-1], true
 }
 
+// printPrompt prints the prompt from currentPrompt.
 func printPrompt(w io.Writer) error {
-	u, err := user.Current()
+	prompt, err := currentPrompt()
 	if err != nil {
 		return err
 	}
-	wd, err := os.Getwd()
+	_, err = io.WriteString(w, prompt)
+	return err
+}
+
+// currentPrompt renders the prompt from the PS1 template, or from defaultPrompt when PS1 is unset.
+func currentPrompt() (string, error) {
+	template, ok := os.LookupEnv("PS1")
+	if !ok {
+		template = defaultPrompt
+	}
+	return renderPrompt(template)
+}
+
+// readLine writes prompt and reads the next line of input, newline included,
+// from the line editor if there is one and from r otherwise.
+func (s *shell) readLine(r *bufio.Reader, w io.Writer, prompt string) (string, error) {
+	if s.editor != nil {
+		return s.editor.readLine(prompt)
+	}
+	s.mu.Lock()
+	_, _ = io.WriteString(w, prompt)
+	s.reading = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.reading = false
+	}()
+	return r.ReadString('\n')
+}
+
+// renderPrompt replaces the escapes in template: \w with the working directory, \u with the username,
+// \h with the hostname, \$ with # for the superuser and $ otherwise, and \\ with a backslash.
+// Other escapes are left as they are.
+func renderPrompt(template string) (string, error) {
+	var prompt strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '\\' || i+1 == len(template) {
+			prompt.WriteByte(template[i])
+			continue
+		}
+		i++
+		switch template[i] {
+		case 'w':
+			wd, err := os.Getwd()
+			if err != nil {
+				return "", err
+			}
+			prompt.WriteString(wd)
+		case 'u':
+			u, err := user.Current()
+			if err != nil {
+				return "", err
+			}
+			prompt.WriteString(u.Username)
+		case 'h':
+			host, err := os.Hostname()
+			if err != nil {
+				return "", err
+			}
+			prompt.WriteString(host)
+		case '$':
+			if os.Geteuid() == 0 {
+				prompt.WriteByte('#')
+			} else {
+				prompt.WriteByte('$')
+			}
+		case '\\':
+			prompt.WriteByte('\\')
+		default:
+			prompt.WriteByte('\\')
+			prompt.WriteByte(template[i])
+		}
+	}
+	return prompt.String(), nil
+}
+
+func (s *shell) handleInput(w io.Writer, input string, exit chan<- struct{}) error {
+	s.exit = exit
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+	tokens, err := tokenize(input)
+	if err == nil {
+		tokens, err = s.expandAliases(tokens)
+	}
 	if err != nil {
+		s.status = exitStatus(err)
 		return err
 	}
-	_, err = fmt.Fprintf(w, "%v [%v] $ ", wd, u.Username)
-	return err
+	list, err := splitList(tokens)
+	if err != nil {
+		s.status = exitStatus(err)
+		return err
+	}
+
+	var (
+		errs listErrors
+		// run is whether the next pipeline runs.
+		run = true
+	)
+	for _, item := range list {
+		if s.exiting {
+			break // an exit skips the rest of the list.
+		}
+		if run {
+			err := s.runPipeline(w, item.tokens, item.op == "&")
+			if s.status = exitStatus(err); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		switch item.op {
+		case "&&":
+			run = s.status == 0
+		case "||":
+			run = s.status != 0
+		default:
+			run = true
+		}
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return errs
+	}
 }
 
-func handleInput(w io.Writer, input string, exit chan<- struct{}) error {
-	input = strings.TrimSpace(input)
-	args := strings.Split(input, " ")
-	name, args := args[0], args[1:]
+// exitStatus is the status a pipeline that returned err exits with, which is 0 on success,
+// the exit code of a command that ran and failed, 128 plus the signal for a command killed by one,
+// 2 for a syntax error, 127 for a command that was not found, and otherwise 1.
+func exitStatus(err error) int {
+	var (
+		exitErr *exec.ExitError
+		status  statusError
+	)
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &status):
+		return int(status)
+	case errors.As(err, &exitErr) && exitErr.ExitCode() > 0:
+		return exitErr.ExitCode()
+	case errors.As(err, &exitErr):
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int(ws.Signal())
+		}
+		return 1
+	case errors.Is(err, ErrSyntax):
+		return 2
+	case errors.Is(err, ErrCommandNotFound):
+		return 127
+	default:
+		return 1
+	}
+}
 
-	switch name {
-	case "cd":
-		return changeDirectory(args...)
-	case "env":
-		return environmentVariables(w, args...)
-	case "exit":
-		exit <- struct{}{}
+// unreported returns err without the failures builtins have already reported to errW, or nil if that is all it was.
+func unreported(err error) error {
+	switch e := err.(type) {
+	case statusError:
 		return nil
-	case "echo":
-		return echo(w, args...)
-	case "pwd":
-		return printWorkingDirectory(w)
-	case "export":
-		return exportVariable(w, args...)
-	case "unset":
-		return unsetVariable(args...)
-	case "history":
-		return showHistory(w)
+	case listErrors:
+		var rest listErrors
+		for _, err := range e {
+			if err = unreported(err); err != nil {
+				rest = append(rest, err)
+			}
+		}
+		switch len(rest) {
+		case 0:
+			return nil
+		case 1:
+			return rest[0]
+		}
+		return rest
 	}
+	return err
+}
 
-	return executeCommand(name, args...)
+// listErrors are the errors of the pipelines of one command line, in the order they ran.
+type listErrors []error
+
+func (e listErrors) Error() string {
+	messages := make([]string, len(e))
+	for i := range e {
+		messages[i] = e[i].Error()
+	}
+	return strings.Join(messages, "\n")
 }
 
-func executeCommand(name string, arg ...string) error {
-	cmd := exec.Command(name, arg...)
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	return cmd.Run()
+// listItem is a pipeline of a command line and the operator after it: ;, &&, ||, &, or empty at the end of the line.
+type listItem struct {
+	tokens []token
+	op     string
 }
 
-// Implementations of the built-in commands:
+// splitList splits tokens into the pipelines between the ;, &&, ||, and & operators.
+func splitList(tokens []token) ([]listItem, error) {
+	var (
+		list  []listItem
+		start int
+	)
+	for i, t := range tokens {
+		if !t.op || t.text != ";" && t.text != "&&" && t.text != "||" && t.text != "&" {
+			continue
+		}
+		if i == start {
+			return nil, fmt.Errorf("%w: unexpected %s", ErrSyntax, t.text)
+		}
+		list = append(list, listItem{tokens: tokens[start:i], op: t.text})
+		start = i + 1
+	}
+	if start < len(tokens) {
+		list = append(list, listItem{tokens: tokens[start:]})
+	} else if n := len(list); n > 0 && (list[n-1].op == "&&" || list[n-1].op == "||") {
+		return nil, fmt.Errorf("%w: %s expects a command after it", ErrSyntax, list[n-1].op)
+	}
+
+	return list, nil
+}
 
-func changeDirectory(args ...string) error {
+// runPipeline runs the builtin or command of tokens, or the pipeline of commands when it has several stages,
+// starting it as a background job instead of waiting for it when background is set.
+// NAME=value words before a command set the variable only while it runs, or for the session without a command.
+// A leading time reports how long the rest took once it's done.
+func (s *shell) runPipeline(w io.Writer, tokens []token, background bool) (err error) {
+	if len(tokens) > 0 && tokens[0] == (token{text: "time"}) {
+		return s.timePipeline(w, tokens[1:], background)
+	}
+	if stages := splitPipeline(tokens); len(stages) > 1 {
+		if background {
+			return fmt.Errorf("background pipelines are not supported")
+		}
+		return s.executePipeline(w, stages...)
+	}
+	assignments, tokens := s.splitAssignments(tokens)
+	args, redirects, err := s.openRedirections(tokens)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// a background job closes its own redirections once it is done.
+		if redirects == nil {
+			return
+		}
+		if closeErr := redirects.close(); err == nil {
+			err = closeErr
+		}
+	}()
+	if err := s.trace(assignments, args); err != nil {
+		return err
+	}
 	if len(args) == 0 {
-		return fmt.Errorf("no path provided")
+		// only assignments and redirections, the redirections having already created or truncated their files.
+		for _, assignment := range assignments {
+			name, value, _ := strings.Cut(assignment, "=")
+			if err := os.Setenv(name, value); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
-	return os.Chdir(args[0])
-}
+	if redirects.stdout != nil {
+		w = redirects.stdout
+	}
+	name, args := args[0], args[1:]
 
-func environmentVariables(w io.Writer, args ...string) error {
-	for _, env := range os.Environ() {
-		_, err := fmt.Fprintln(w, env)
+	if b, ok := builtinCommands[name]; ok {
+		restore, err := assignTemporarily(assignments)
 		if err != nil {
 			return err
 		}
+		defer restore()
+		return b.run(s, w, s.stderr(), args)
 	}
-	return nil
+
+	env := commandEnv(assignments)
+	if background {
+		err = s.startJob(w, redirects, env, strings.Join(append([]string{name}, args...), " ")+" &", name, args...)
+		redirects = nil
+		return err
+	}
+	return s.executeCommand(w, redirects.stdin, env, name, args...)
 }
 
-func echo(w io.Writer, args ...string) error {
-	_, err := fmt.Fprintln(w, strings.Join(args, " "))
+// timePipeline runs the pipeline of tokens and then reports how long it took to stderr, as reportTime does.
+func (s *shell) timePipeline(w io.Writer, tokens []token, background bool) error {
+	return reportTime(s.stderr(), func() error {
+		return s.runPipeline(w, tokens, background)
+	})
+}
+
+// reportTime calls run and then prints to errW the wall-clock time it took, and the user and system CPU time
+// of the commands it waited for, so the report stays out of the output of what was run.
+func reportTime(errW io.Writer, run func() error) error {
+	userBefore, sysBefore := childCPUTime()
+	start := time.Now()
+	err := run()
+	elapsed := time.Since(start)
+	userAfter, sysAfter := childCPUTime()
+
+	user, sys := userAfter-userBefore, sysAfter-sysBefore
+	_, _ = fmt.Fprintf(errW, "\nreal\t%s\nuser\t%s\nsys\t%s\n", formatDuration(elapsed), formatDuration(user), formatDuration(sys))
 	return err
 }
 
-func printWorkingDirectory(w io.Writer) error {
+// runWords runs the builtin or command named by the first of words, which are already expanded, with the rest as its arguments.
+func (s *shell) runWords(w io.Writer, words []string) error {
+	if len(words) == 0 {
+		return nil
+	}
+	if b, ok := builtinCommands[words[0]]; ok {
+		return b.run(s, w, s.stderr(), words[1:])
+	}
+	return s.executeCommand(w, nil, nil, words[0], words[1:]...)
+}
+
+// formatDuration formats d in minutes and seconds to the millisecond, such as 1m2.345s.
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%dm%.3fs", d/time.Minute, (d % time.Minute).Seconds())
+}
+
+// trace prints the assignments and words of a command to stderr after a +, if xtrace is on.
+func (s *shell) trace(assignments, args []string) error {
+	if !s.options.xtrace || len(assignments)+len(args) == 0 {
+		return nil
+	}
+	words := append(append([]string{}, assignments...), args...)
+	_, err := fmt.Fprintln(s.stderr(), "+", strings.Join(words, " "))
+	return err
+}
+
+// splitAssignments splits the NAME=value words that start a command off tokens,
+// returning them with their values expanded, and the tokens after them.
+func (s *shell) splitAssignments(tokens []token) ([]string, []token) {
+	var assignments []string
+	for len(tokens) > 0 && !tokens[0].op {
+		name, value, ok := strings.Cut(tokens[0].text, "=")
+		if !ok || !isName(name) {
+			break
+		}
+		assignments = append(assignments, name+"="+s.expandWord(value))
+		tokens = tokens[1:]
+	}
+	return assignments, tokens
+}
+
+// commandEnv returns the environment of a command run with assignments,
+// or nil for the shell's own environment when there are none.
+func commandEnv(assignments []string) []string {
+	if len(assignments) == 0 {
+		return nil
+	}
+	env := os.Environ()
+	for _, assignment := range assignments {
+		env = setEnv(env, assignment)
+	}
+	return env
+}
+
+// assignTemporarily sets each NAME=value of assignments in the environment,
+// returning a func that puts back what they replaced.
+func assignTemporarily(assignments []string) (func(), error) {
+	var restores []func()
+	restore := func() {
+		for i := len(restores) - 1; i >= 0; i-- {
+			restores[i]()
+		}
+	}
+	for _, assignment := range assignments {
+		name, value, _ := strings.Cut(assignment, "=")
+		old, set := os.LookupEnv(name)
+		if err := os.Setenv(name, value); err != nil {
+			restore()
+			return nil, err
+		}
+		restores = append(restores, func() {
+			if set {
+				_ = os.Setenv(name, old)
+			} else {
+				_ = os.Unsetenv(name)
+			}
+		})
+	}
+	return restore, nil
+}
+
+// builtin runs a command within the shell s, writing its output to w and any diagnostics, such as warnings, to errW.
+// A builtin that has reported its own failure to errW returns a statusError, which sets the status without being printed again.
+type builtin func(s *shell, w, errW io.Writer, args []string) error
+
+// statusError is the error of a builtin that has already written its diagnostics to errW,
+// failing with the status without anything more to report.
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// reportFailure writes err to errW as a diagnostic of the builtin name, followed by its usage when it was given
+// the wrong arguments, returning the statusError the builtin fails with: 2 after the usage, otherwise 1.
+// It returns nil for a nil err.
+func reportFailure(errW io.Writer, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	_, _ = fmt.Fprintf(errW, "%s: %v\n", name, err)
+	if errors.Is(err, builtins.ErrInvalidArgCount) {
+		_, _ = fmt.Fprintf(errW, "usage: %s\n", builtinCommands[name].usage)
+		return statusError(2)
+	}
+	return statusError(1)
+}
+
+// builtinCommand is a builtin along with the help printed for it.
+type builtinCommand struct {
+	run builtin
+	// usage shows the arguments the builtin takes, such as "cd [dir]", and summary says what it does in a line.
+	usage, summary string
+}
+
+// builtinCommands are the commands run by the shell itself rather than as external commands, by name.
+// It is filled in by init, since some of the builtins look commands up in it.
+var builtinCommands map[string]builtinCommand
+
+func init() {
+	builtinCommands = map[string]builtinCommand{
+		"cd": {
+			usage:   "cd [dir | -]",
+			summary: "Change the working directory to dir, the home directory, or the previous directory.",
+			run: func(_ *shell, _, errW io.Writer, args []string) error {
+				return reportFailure(errW, "cd", builtins.ChangeDirectory(args...))
+			},
+		},
+		"env": {
+			usage:   "env [-i] [NAME=VALUE ...] [command [arg ...]]",
+			summary: "Print the environment, or run a command in a changed copy of it.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.environmentVariables(w, args...)
+			},
+		},
+		"exit": {
+			usage:   "exit [status]",
+			summary: "Exit the shell with status, or with the last command's status.",
+			run: func(s *shell, _, _ io.Writer, args []string) error {
+				return s.exitShell(args...)
+			},
+		},
+		"echo": {
+			usage:   "echo [-ne] [arg ...]",
+			summary: "Print the arguments separated by spaces.",
+			run: func(_ *shell, w, _ io.Writer, args []string) error {
+				return echo(w, args...)
+			},
+		},
+		"pwd": {
+			usage:   "pwd",
+			summary: "Print the working directory.",
+			run: func(_ *shell, w, _ io.Writer, _ []string) error {
+				return printWorkingDirectory(w)
+			},
+		},
+		"export": {
+			usage:   "export NAME=VALUE ...",
+			summary: "Set environment variables for the commands the shell runs.",
+			run: func(_ *shell, _, _ io.Writer, args []string) error {
+				return builtins.ExportVariable(args...)
+			},
+		},
+		"unset": {
+			usage:   "unset NAME ...",
+			summary: "Remove variables from the environment.",
+			run: func(_ *shell, _, _ io.Writer, args []string) error {
+				return builtins.UnsetVariable(args...)
+			},
+		},
+		"history": {
+			usage:   "history [-c]",
+			summary: "Print the numbered history, or clear it.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.showHistory(w, args...)
+			},
+		},
+		"alias": {
+			usage:   "alias [name[=value] ...]",
+			summary: "Define aliases, or print them.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.alias(w, args...)
+			},
+		},
+		"unalias": {
+			usage:   "unalias name ...",
+			summary: "Remove aliases.",
+			run: func(s *shell, _, _ io.Writer, args []string) error {
+				return s.unalias(args...)
+			},
+		},
+		"source": {
+			usage:   "source file [arg ...]",
+			summary: "Run the commands in file in this shell, with args as $1 and on.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.source(w, s.exit, args...)
+			},
+		},
+		"which": {
+			usage:   "which name ...",
+			summary: "Print the builtin or executable each name runs.",
+			run: func(_ *shell, w, _ io.Writer, args []string) error {
+				return which(w, args...)
+			},
+		},
+		"type": {
+			usage:   "type name ...",
+			summary: "Print whether each name is a builtin, an alias, or an executable.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.typeOf(w, args...)
+			},
+		},
+		"jobs": {
+			usage:   "jobs",
+			summary: "List the background jobs.",
+			run: func(s *shell, w, errW io.Writer, args []string) error {
+				return reportFailure(errW, "jobs", s.listJobs(w, args...))
+			},
+		},
+		"fg": {
+			usage:   "fg [%job]",
+			summary: "Bring a job to the foreground and wait for it.",
+			run: func(s *shell, w, errW io.Writer, args []string) error {
+				return s.foregroundJob(w, errW, args...)
+			},
+		},
+		"bg": {
+			usage:   "bg [%job]",
+			summary: "Continue a stopped job in the background.",
+			run: func(s *shell, w, errW io.Writer, args []string) error {
+				return s.backgroundJob(w, errW, args...)
+			},
+		},
+		"read": {
+			usage:   "read [-p prompt] [name ...]",
+			summary: "Read a line of input into variables.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.read(w, args...)
+			},
+		},
+		"umask": {
+			usage:   "umask [mode]",
+			summary: "Print or set the file mode creation mask.",
+			run: func(_ *shell, w, _ io.Writer, args []string) error {
+				return builtins.Umask(w, args...)
+			},
+		},
+		"kill": {
+			usage:   "kill [-signal] pid | %job ...",
+			summary: "Send a signal to processes or jobs.",
+			run: func(s *shell, _, errW io.Writer, args []string) error {
+				return s.kill(errW, args...)
+			},
+		},
+		"path": {
+			usage:   "path [add | prepend | remove dir]",
+			summary: "Print the PATH entries, or add, prepend, or remove one.",
+			run: func(_ *shell, w, errW io.Writer, args []string) error {
+				return reportFailure(errW, "path", builtins.Path(w, args...))
+			},
+		},
+		"pushd": {
+			usage:   "pushd [dir]",
+			summary: "Change to dir, saving the working directory on the directory stack.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.pushDirectory(w, args...)
+			},
+		},
+		"popd": {
+			usage:   "popd",
+			summary: "Change to the directory on top of the directory stack, removing it.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.popDirectory(w, args...)
+			},
+		},
+		"dirs": {
+			usage:   "dirs [-c]",
+			summary: "Print the directory stack, or clear it.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.directories(w, args...)
+			},
+		},
+		"clear": {
+			usage:   "clear [-f]",
+			summary: "Clear the terminal screen.",
+			run: func(_ *shell, w, errW io.Writer, args []string) error {
+				return clearScreen(w, errW, args...)
+			},
+		},
+		"set": {
+			usage:   "set [-o | +o name ...]",
+			summary: "Turn options on with -o or off with +o, or print them with a bare -o.",
+			run: func(s *shell, w, _ io.Writer, args []string) error {
+				return s.setOptions(w, args...)
+			},
+		},
+		"time": {
+			usage:   "time [pipeline]",
+			summary: "Run the pipeline, then print the real, user, and system time it took to stderr.",
+			run: func(s *shell, w, errW io.Writer, args []string) error {
+				// a leading time is run by runPipeline, which keeps its pipeline whole,
+				// so this is only reached after NAME=value words, with the command already expanded.
+				return reportTime(errW, func() error {
+					return s.runWords(w, args)
+				})
+			},
+		},
+		"help": {
+			usage:   "help [name ...]",
+			summary: "Print the builtins, or the help of each name.",
+			run: func(_ *shell, w, _ io.Writer, args []string) error {
+				return help(w, args...)
+			},
+		},
+	}
+	builtinCommands["."] = builtinCommand{
+		run:     builtinCommands["source"].run,
+		usage:   ". file [arg ...]",
+		summary: "Run the commands in file in this shell, as source does.",
+	}
+	builtinCommands["cls"] = builtinCommand{
+		run:     builtinCommands["clear"].run,
+		usage:   "cls [-f]",
+		summary: "Clear the terminal screen, as clear does.",
+	}
+}
+
+// setOptions turns on the option after each -o in args and turns off the one after each +o.
+// A lone -o prints whether each option is on or off instead.
+func (s *shell) setOptions(w io.Writer, args ...string) error {
+	if len(args) == 1 && args[0] == "-o" {
+		for _, name := range optionNames {
+			on, _ := s.options.option(name)
+			state := "off"
+			if *on {
+				state = "on"
+			}
+			if _, err := fmt.Fprintf(w, "%-15s%s\n", name, state); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(args) == 0 || len(args)%2 != 0 {
+		return fmt.Errorf("%w: expected -o or +o followed by an option name", builtins.ErrInvalidArgCount)
+	}
+
+	for i := 0; i < len(args); i += 2 {
+		if args[i] != "-o" && args[i] != "+o" {
+			return fmt.Errorf("set: %s: invalid flag, expected -o or +o", args[i])
+		}
+		on, ok := s.options.option(args[i+1])
+		if !ok {
+			return fmt.Errorf("set: %s: invalid option name", args[i+1])
+		}
+		*on = args[i] == "-o"
+	}
+	return nil
+}
+
+// pushDirectory changes to the directory in args, pushing the one it left onto the directory stack,
+// and prints the stack. Without args it swaps the working directory with the top of the stack instead.
+func (s *shell) pushDirectory(w io.Writer, args ...string) error {
+	var dir string
+	switch len(args) {
+	case 0:
+		if len(s.dirStack) == 0 {
+			return errors.New("pushd: no other directory")
+		}
+		dir = s.dirStack[len(s.dirStack)-1]
+	case 1:
+		dir = args[0]
+	default:
+		return fmt.Errorf("%w: expected zero or one arguments (directory)", builtins.ErrInvalidArgCount)
+	}
+
 	wd, err := os.Getwd()
 	if err != nil {
 		return err
 	}
-	_, err = fmt.Fprintln(w, wd)
-	return err
+	if err := builtins.ChangeDirectory(dir); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		s.dirStack[len(s.dirStack)-1] = wd
+	} else {
+		s.dirStack = append(s.dirStack, wd)
+	}
+	return s.directories(w)
 }
 
-func exportVariable(w io.Writer, args ...string) error {
-	// This is a placeholder; setting environment variables in Go is not straightforward.
-	return nil
+// popDirectory changes to the directory on top of the directory stack, removing it, and prints the stack.
+func (s *shell) popDirectory(w io.Writer, args ...string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: expected zero arguments", builtins.ErrInvalidArgCount)
+	}
+	if len(s.dirStack) == 0 {
+		return errors.New("popd: directory stack empty")
+	}
+	if err := builtins.ChangeDirectory(s.dirStack[len(s.dirStack)-1]); err != nil {
+		return err
+	}
+	s.dirStack = s.dirStack[:len(s.dirStack)-1]
+	return s.directories(w)
 }
 
-func unsetVariable(args ...string) error {
-	// This is a placeholder; unsetting environment variables in Go is not straightforward.
-	return nil
+// directories prints the working directory followed by the directory stack from its top, or clears the stack given -c.
+func (s *shell) directories(w io.Writer, args ...string) error {
+	switch {
+	case len(args) == 0:
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		dirs := []string{wd}
+		for i := len(s.dirStack) - 1; i >= 0; i-- {
+			dirs = append(dirs, s.dirStack[i])
+		}
+		_, err = fmt.Fprintln(w, strings.Join(dirs, " "))
+		return err
+	case len(args) == 1 && args[0] == "-c":
+		s.dirStack = nil
+		return nil
+	default:
+		return fmt.Errorf("%w: expected no arguments or -c", builtins.ErrInvalidArgCount)
+	}
 }
 
-func showHistory(w io.Writer) error {
-	// This is a placeholder; implementing history requires additional logic.
-	return nil
+// clearScreen clears the terminal w with the escapes that move the cursor home and erase the screen.
+// Output that isn't a terminal is left alone with a note to errW, unless -f forces the escapes to be written anyway,
+// so a clear in a script or pipeline does no harm.
+func clearScreen(w, errW io.Writer, args ...string) error {
+	force := len(args) == 1 && args[0] == "-f"
+	if len(args) > 0 && !force {
+		return fmt.Errorf("%w: expected no arguments or -f", builtins.ErrInvalidArgCount)
+	}
+	if f, ok := w.(*os.File); !force && (!ok || !isTerminal(f)) {
+		_, err := fmt.Fprintln(errW, "clear: output is not a terminal, use -f to clear it anyway")
+		return err
+	}
+	_, err := io.WriteString(w, "\x1b[H\x1b[2J")
+	return err
+}
+
+// registerBuiltin adds a builtin command with its help, replacing any builtin of the same name.
+func registerBuiltin(name, usage, summary string, run builtin) {
+	builtinCommands[name] = builtinCommand{run: run, usage: usage, summary: summary}
+}
+
+// pipelineNote ends the list of builtins: the stages of a pipeline run as external commands,
+// so a builtin there is looked up in PATH like any other command.
+const pipelineNote = "\nBuiltins run in the shell itself, so they can't be a stage of a pipeline; redirect their output instead."
+
+// help prints the usage and summary of each builtin in names, or of every builtin when there are none,
+// followed by a note that builtins can't be a stage of a pipeline. The names that are not builtins are reported together in the returned error.
+func help(w io.Writer, names ...string) error {
+	if len(names) == 0 {
+		for name := range builtinCommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, name := range names {
+			_, _ = fmt.Fprintf(table, "%s\t%s\n", builtinCommands[name].usage, builtinCommands[name].summary)
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(w, pipelineNote)
+		return err
+	}
+
+	var missing []string
+	for _, name := range names {
+		b, ok := builtinCommands[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n    %s\n", name, b.usage, b.summary); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("help: no builtin named %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// isBuiltin reports whether name is run by the shell itself rather than as an external command.
+func isBuiltin(name string) bool {
+	_, ok := builtinCommands[name]
+	return ok
+}
+
+// which prints what runs for each name: that it is a built-in, or the path of the executable found in PATH.
+// The names that are neither are reported together in the returned error.
+func which(w io.Writer, names ...string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("%w: expected at least one argument (name)", builtins.ErrInvalidArgCount)
+	}
+	var missing []string
+	for _, name := range names {
+		if isBuiltin(name) {
+			if _, err := fmt.Fprintf(w, "%s: shell built-in command\n", name); err != nil {
+				return err
+			}
+			continue
+		}
+		path, err := exec.LookPath(name)
+		if err != nil {
+			missing = append(missing, name)
+			continue
+		}
+		if _, err := fmt.Fprintln(w, path); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("which: %s not found", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// typeOf prints how each name runs as a command: as a built-in, as an alias, or as the executable found in PATH.
+// The names that are none of these are reported together in the returned error.
+func (s *shell) typeOf(w io.Writer, names ...string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("%w: expected at least one argument (name)", builtins.ErrInvalidArgCount)
+	}
+	var missing []string
+	for _, name := range names {
+		var err error
+		if isBuiltin(name) {
+			_, err = fmt.Fprintf(w, "%s is a shell builtin\n", name)
+		} else if value, ok := s.aliases[name]; ok {
+			_, err = fmt.Fprintf(w, "%s is aliased to `%s'\n", name, value)
+		} else if path, lookErr := exec.LookPath(name); lookErr == nil {
+			_, err = fmt.Fprintf(w, "%s is %s\n", name, path)
+		} else {
+			missing = append(missing, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("type: %s not found", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// startJob starts an external command without waiting for it, printing its job number and PID.
+// The job takes over redirects, closing them once the command exits. A nil env runs it in the shell's environment.
+func (s *shell) startJob(w io.Writer, redirects *redirections, env []string, line, name string, arg ...string) error {
+	cmd := exec.Command(name, arg...)
+	cmd.Env = env
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = w
+	if redirects.stdout != nil {
+		cmd.Stdout = redirects.stdout
+	}
+	cmd.Stdin = redirects.stdin
+	if err := startCommand(cmd); err != nil {
+		_ = redirects.close()
+		return err
+	}
+
+	j := &job{id: 1, line: line, cmd: cmd, done: make(chan struct{})}
+	if len(s.jobs) > 0 {
+		j.id = s.jobs[len(s.jobs)-1].id + 1
+	}
+	s.jobs = append(s.jobs, j)
+	go func() {
+		// reap the command so it does not linger as a zombie.
+		j.err = cmd.Wait()
+		if closeErr := redirects.close(); j.err == nil {
+			j.err = closeErr
+		}
+		close(j.done)
+	}()
+
+	_, err := fmt.Fprintf(w, "[%d] %d\n", j.id, cmd.Process.Pid)
+	return err
+}
+
+// reportJobs prints and forgets the background jobs that are done.
+func (s *shell) reportJobs(w io.Writer) {
+	running := s.jobs[:0]
+	for _, j := range s.jobs {
+		if j.finished() {
+			_, _ = fmt.Fprintf(w, "[%d] %s\t%s\n", j.id, j.state(), j.line)
+		} else {
+			running = append(running, j)
+		}
+	}
+	s.jobs = running
+}
+
+// listJobs prints every background job with its state, then forgets the ones that are done.
+func (s *shell) listJobs(w io.Writer, args ...string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%w: expected zero arguments", builtins.ErrInvalidArgCount)
+	}
+	for _, j := range s.jobs {
+		if !j.finished() {
+			if _, err := fmt.Fprintf(w, "[%d] %d %s\t%s\n", j.id, j.cmd.Process.Pid, j.state(), j.line); err != nil {
+				return err
+			}
+		}
+	}
+	s.reportJobs(w)
+	return nil
+}
+
+// findJob returns the job of spec, which is %n or n for job n, or the most recent job when spec is empty.
+func (s *shell) findJob(spec string) (*job, error) {
+	if spec == "" {
+		if len(s.jobs) == 0 {
+			return nil, fmt.Errorf("no current job")
+		}
+		return s.jobs[len(s.jobs)-1], nil
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(spec, "%"))
+	if err == nil {
+		for _, j := range s.jobs {
+			if j.id == id {
+				return j, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s: no such job", spec)
+}
+
+// jobSpec is the single optional job spec of the fg and bg args.
+func jobSpec(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return "", nil
+	case 1:
+		return args[0], nil
+	default:
+		return "", fmt.Errorf("%w: expected zero or one arguments (job)", builtins.ErrInvalidArgCount)
+	}
+}
+
+// foregroundJob continues a background job in the foreground, waiting for it to exit and failing as it did.
+// A job that can't be brought to the foreground is reported to errW.
+func (s *shell) foregroundJob(w, errW io.Writer, args ...string) error {
+	spec, err := jobSpec(args)
+	if err != nil {
+		return reportFailure(errW, "fg", err)
+	}
+	j, err := s.findJob(spec)
+	if err != nil {
+		return reportFailure(errW, "fg", err)
+	}
+	if _, err := fmt.Fprintln(w, strings.TrimSuffix(j.line, " &")); err != nil {
+		return err
+	}
+	if j.stopped {
+		if err := continueProcess(j.cmd.Process); err != nil && !j.finished() {
+			return reportFailure(errW, "fg", err)
+		}
+		j.stopped = false
+	}
+
+	done := s.foreground(j.cmd)
+	<-j.done
+	done()
+	for i := range s.jobs {
+		if s.jobs[i] == j {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			break
+		}
+	}
+	return j.err
+}
+
+// backgroundJob continues a stopped background job, leaving it in the background.
+// A job that can't be continued is reported to errW.
+func (s *shell) backgroundJob(w, errW io.Writer, args ...string) error {
+	spec, err := jobSpec(args)
+	if err != nil {
+		return reportFailure(errW, "bg", err)
+	}
+	j, err := s.findJob(spec)
+	if err != nil {
+		return reportFailure(errW, "bg", err)
+	}
+	if !j.stopped {
+		return reportFailure(errW, "bg", fmt.Errorf("job %d is already running", j.id))
+	}
+	if err := continueProcess(j.cmd.Process); err != nil && !j.finished() {
+		return reportFailure(errW, "bg", err)
+	}
+	j.stopped = false
+	_, err = fmt.Fprintf(w, "[%d] %s\n", j.id, j.line)
+	return err
+}
+
+// parseSignal parses the signal of a kill flag without its -, which is a name like TERM or SIGTERM, or a number.
+func parseSignal(flag string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(flag); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := signals[strings.TrimPrefix(strings.ToUpper(flag), "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("%s: invalid signal", flag)
+}
+
+// kill sends a signal to each PID or %n job spec in args, SIGTERM unless the first argument is a -SIGNAL or -N flag.
+// Each target that could not be signalled is reported to errW as it comes, and the rest are still signalled.
+func (s *shell) kill(errW io.Writer, args ...string) error {
+	sig := syscall.SIGTERM
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		var err error
+		if sig, err = parseSignal(args[0][1:]); err != nil {
+			return reportFailure(errW, "kill", err)
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return reportFailure(errW, "kill", fmt.Errorf("%w: expected at least one argument (pid or job)", builtins.ErrInvalidArgCount))
+	}
+
+	var failed bool
+	for _, target := range args {
+		if err := s.signal(target, sig); err != nil {
+			_, _ = fmt.Fprintf(errW, "kill: %v\n", err)
+			failed = true
+		}
+	}
+	if failed {
+		return statusError(1)
+	}
+	return nil
+}
+
+// signal sends sig to target, which is a PID or a %n job spec, keeping track of whether a job is stopped.
+func (s *shell) signal(target string, sig syscall.Signal) error {
+	var p *os.Process
+	if strings.HasPrefix(target, "%") {
+		j, err := s.findJob(target)
+		if err != nil {
+			return err
+		}
+		p = j.cmd.Process
+	} else {
+		pid, err := strconv.Atoi(target)
+		if err != nil || pid <= 0 {
+			return fmt.Errorf("%s: arguments must be process or job IDs", target)
+		}
+		// FindProcess always succeeds on Unix, so an unknown PID is only found out by the signal.
+		if p, err = os.FindProcess(pid); err != nil {
+			return fmt.Errorf("%s: %w", target, err)
+		}
+	}
+	if err := p.Signal(sig); err != nil {
+		return fmt.Errorf("%s: %w", target, err)
+	}
+
+	for _, j := range s.jobs {
+		if j.cmd.Process.Pid != p.Pid {
+			continue
+		}
+		if stopped, ok := jobStates[sig]; ok {
+			j.stopped = stopped
+		}
+	}
+	return nil
+}
+
+// executeCommand runs an external command in the foreground, with env as its environment or else the shell's.
+func (s *shell) executeCommand(w io.Writer, stdin io.Reader, env []string, name string, arg ...string) error {
+	cmd := exec.Command(name, arg...)
+	cmd.Env = env
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = w
+	cmd.Stdin = stdin
+	if err := startCommand(cmd); err != nil {
+		return err
+	}
+	defer s.foreground(cmd)()
+	return cmd.Wait()
+}
+
+// startCommand starts cmd, reporting a program that is not in PATH as ErrCommandNotFound.
+func startCommand(cmd *exec.Cmd) error {
+	err := cmd.Start()
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("gosh: %w: %s", ErrCommandNotFound, cmd.Args[0])
+	}
+	return err
+}
+
+// foreground records cmds as running in the foreground, so an interrupt goes to them rather than the shell,
+// returning a func to call once they are done.
+func (s *shell) foreground(cmds ...*exec.Cmd) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = cmds
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.running = nil
+	}
+}
+
+// interrupt forwards an interrupt to the commands running in the foreground that the terminal did not send it to,
+// or when there are none and the loop is waiting for input, abandons the line being typed for a fresh prompt.
+func (s *shell) interrupt(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.running) == 0 {
+		if s.reading && !s.noPrompt {
+			_, _ = fmt.Fprintln(w)
+			_ = printPrompt(w)
+		}
+		return
+	}
+	for _, cmd := range s.running {
+		// a Ctrl-C at the terminal already went to each process in its foreground group.
+		if !inTerminalForeground(cmd.Process.Pid) {
+			_ = cmd.Process.Signal(os.Interrupt)
+		}
+	}
+}
+
+var (
+	ErrSyntax          = errors.New("syntax error")
+	ErrCommandNotFound = errors.New("command not found")
+
+	// ErrClobber is the error of > with noclobber set when its file already exists.
+	ErrClobber = errors.New("cannot overwrite existing file")
+
+	// errUnterminated is the syntax error of a quote left open, which a line continues from.
+	errUnterminated = fmt.Errorf("%w: unterminated", ErrSyntax)
+)
+
+// token is a word of a command line as written, with its quotes and variables still to be expanded by expandWord,
+// or an operator such as | or > when op is set.
+type token struct {
+	text string
+	op   bool
+	// body is the text of the here-document the word is the delimiter of, after a << operator.
+	body string
+}
+
+// operators are the unquoted operators that end a word, longest first so >> is not read as two >.
+var operators = []string{"<<<", ">>", ">|", "<<", "&&", "||", "|", "&", ";", "<", ">"}
+
+// tokenize splits a command line into words at unquoted whitespace and operators.
+// Quoted and unquoted parts next to each other make one word.
+// An unquoted # at the start of a word begins a comment, which runs to the end of the input.
+// The lines after the one with a << delimiter are the body of its here-document, up to a line of just the delimiter.
+func tokenize(input string) ([]token, error) {
+	var (
+		tokens []token
+		// start is where the current word began, or -1 between words.
+		start = -1
+		// hereDocs are the indexes of the delimiters in tokens whose bodies start on the next line.
+		hereDocs []int
+	)
+	endWord := func(i int) {
+		if start >= 0 {
+			tokens = append(tokens, token{text: input[start:i]})
+			start = -1
+			if n := len(tokens); n > 1 && tokens[n-2] == (token{text: "<<", op: true}) {
+				hereDocs = append(hereDocs, n-1)
+			}
+		}
+	}
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			endWord(i)
+			if c == '\n' && len(hereDocs) > 0 {
+				for _, d := range hereDocs {
+					body, next, ok := hereDocBody(input, i+1, unquote(tokens[d].text))
+					if !ok {
+						return nil, fmt.Errorf("%w here-document", errUnterminated)
+					}
+					tokens[d].body = body
+					i = next - 1
+				}
+				hereDocs = nil
+			}
+			continue
+		}
+		if op := operatorAt(input, i); op != "" {
+			endWord(i)
+			tokens = append(tokens, token{text: op, op: true})
+			i += len(op) - 1
+			continue
+		}
+		if start < 0 {
+			if c == '#' {
+				// a # starting a word comments out the rest of the line.
+				break
+			}
+			start = i
+		}
+		switch c {
+		case '\\':
+			i++
+		case '\'':
+			end := strings.IndexByte(input[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w '", errUnterminated)
+			}
+			i += 1 + end
+		case '"':
+			if i = closingDoubleQuote(input, i); i < 0 {
+				return nil, fmt.Errorf(`%w "`, errUnterminated)
+			}
+		}
+	}
+	endWord(len(input))
+	if len(hereDocs) > 0 {
+		return nil, fmt.Errorf("%w here-document", errUnterminated)
+	}
+
+	return tokens, nil
+}
+
+// hereDocBody returns the lines of input from start up to the line that is just delim, and the index after that line.
+// It is not ok if there is no such line.
+func hereDocBody(input string, start int, delim string) (string, int, bool) {
+	for i := start; i < len(input); {
+		end := strings.IndexByte(input[i:], '\n')
+		if end < 0 {
+			end = len(input) - i
+		}
+		if input[i:i+end] == delim {
+			return input[start:i], i + end + 1, true
+		}
+		i += end + 1
+	}
+	return "", 0, false
+}
+
+// unquote removes the quotes and backslashes of word without expanding it, as for a here-document delimiter.
+func unquote(word string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\'' || r == '"' || r == '\\' {
+			return -1
+		}
+		return r
+	}, word)
+}
+
+// operatorAt returns the operator starting at s[i], or empty if there is none.
+func operatorAt(s string, i int) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s[i:], op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// closingDoubleQuote returns the index of the " closing the one at s[i], skipping escaped quotes, or -1 if there is none.
+func closingDoubleQuote(s string, i int) int {
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// expandWord removes the quotes of a word and expands its variables, and a leading tilde.
+// Within single quotes everything is literal. Within double quotes variables are expanded,
+// and a backslash escapes a ", \, or $. Elsewhere variables are expanded and a backslash escapes any character.
+// An unquoted ~ or ~user up to the first / at the start of the word is a home directory, see expandTilde.
+func (s *shell) expandWord(word string) string {
+	return s.expand(word).word.String()
+}
+
+// expandFields expands a word like expandWord, then when it has unquoted wildcards,
+// into the sorted paths matching it as a glob pattern. A pattern matching nothing is left as it is.
+func (s *shell) expandFields(word string) []string {
+	e := s.expand(word)
+	if e.glob {
+		if matches, err := filepath.Glob(e.pattern.String()); err == nil && len(matches) > 0 {
+			return matches
+		}
+	}
+	return []string{e.word.String()}
+}
+
+// expansion is an expanded word alongside the glob pattern matching it, in which only unquoted wildcards are special.
+type expansion struct {
+	word, pattern strings.Builder
+	// glob is set once the word has an unquoted wildcard.
+	glob bool
+}
+
+// quoted appends text that is matched literally.
+func (e *expansion) quoted(text string) {
+	e.word.WriteString(text)
+	for i := 0; i < len(text); i++ {
+		if strings.IndexByte(`*?[\`, text[i]) >= 0 {
+			e.pattern.WriteByte('\\')
+		}
+		e.pattern.WriteByte(text[i])
+	}
+}
+
+// unquoted appends c, which is a wildcard when it is *, ?, or [.
+func (e *expansion) unquoted(c byte) {
+	e.word.WriteByte(c)
+	e.pattern.WriteByte(c)
+	if c == '*' || c == '?' || c == '[' {
+		e.glob = true
+	}
+}
+
+// expand removes the quotes of a word and expands its variables, as described for expandWord.
+func (s *shell) expand(word string) *expansion {
+	e := &expansion{}
+	i := 0
+	if home, n := expandTilde(word); n > 0 {
+		e.quoted(home)
+		i = n
+	}
+	for ; i < len(word); i++ {
+		switch c := word[i]; c {
+		case '\\':
+			if i+1 < len(word) {
+				i++
+				e.quoted(word[i : i+1])
+			}
+		case '\'':
+			end := strings.IndexByte(word[i+1:], '\'')
+			if end < 0 {
+				end = len(word) - i - 1
+			}
+			e.quoted(word[i+1 : i+1+end])
+			i += 1 + end
+		case '"':
+			end := closingDoubleQuote(word, i)
+			if end < 0 {
+				end = len(word)
+			}
+			for i++; i < end; i++ {
+				switch {
+				case word[i] == '\\' && i+1 < end && strings.IndexByte(`"\$`, word[i+1]) >= 0:
+					i++
+					e.quoted(word[i : i+1])
+				case word[i] == '$':
+					value, next := s.expandVariable(word[:end], i)
+					e.quoted(value)
+					i = next - 1
+				default:
+					e.quoted(word[i : i+1])
+				}
+			}
+		case '$':
+			value, next := s.expandVariable(word, i)
+			e.quoted(value)
+			i = next - 1
+		default:
+			e.unquoted(c)
+		}
+	}
+	return e
+}
+
+// expandTilde expands a tilde prefix at the start of word, returning the home directory and the length of the prefix,
+// or 0 when word does not start with one. The prefix runs to the first /, and is ~ for $HOME, or else ~user for the user's home.
+// A prefix with quotes or variables, or naming an unknown user, is not expanded.
+func expandTilde(word string) (string, int) {
+	if !strings.HasPrefix(word, "~") {
+		return "", 0
+	}
+	prefix, _, _ := strings.Cut(word, "/")
+	name := prefix[1:]
+	if strings.ContainsAny(name, `'"\$~`) {
+		return "", 0
+	}
+	if name == "" {
+		if home := os.Getenv("HOME"); home != "" {
+			return home, len(prefix)
+		}
+		if builtins.HomeDir == "" {
+			return "", 0
+		}
+		return builtins.HomeDir, len(prefix)
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", 0
+	}
+	return u.HomeDir, len(prefix)
+}
+
+// expandVariable expands the variable reference at word[i], which is a $, returning its value and the index after it.
+// $NAME and ${NAME} are the environment variable, empty when unset, $$ is the shell's PID,
+// $? is the exit status of the last pipeline, and $1 to $9 and $# are the script's arguments and their count,
+// with ${10} and on for the arguments after the ninth. A $ that does not start a reference is kept.
+func (s *shell) expandVariable(word string, i int) (string, int) {
+	switch {
+	case i+1 == len(word):
+		return "$", i + 1
+	case strings.IndexByte("$?#123456789", word[i+1]) >= 0:
+		return s.specialVariable(word[i+1 : i+2]), i + 2
+	case word[i+1] == '{':
+		end := strings.IndexByte(word[i+2:], '}')
+		if end < 0 {
+			// unterminated, so not a variable.
+			return "$", i + 1
+		}
+		return s.specialVariable(word[i+2 : i+2+end]), i + 3 + end
+	}
+	end := i + 1
+	for end < len(word) && isNameByte(word[end], end == i+1) {
+		end++
+	}
+	if end == i+1 {
+		// not followed by a name, so a literal $.
+		return "$", i + 1
+	}
+	return os.Getenv(word[i+1 : end]), end
+}
+
+// specialVariable is the value of the $, ?, or # parameter or of a positional parameter,
+// or else the environment variable name. A positional parameter past the last argument is empty.
+func (s *shell) specialVariable(name string) string {
+	switch name {
+	case "$":
+		return strconv.Itoa(os.Getpid())
+	case "?":
+		return strconv.Itoa(s.status)
+	case "#":
+		return strconv.Itoa(len(s.params))
+	}
+	if n, err := strconv.Atoi(name); err == nil && n > 0 {
+		if n > len(s.params) {
+			return ""
+		}
+		return s.params[n-1]
+	}
+	return os.Getenv(name)
+}
+
+// isName reports whether name is a valid variable name.
+func isName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isNameByte(name[i], i == 0) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// isNameByte reports whether c can be part of a variable name, where a name cannot start with a digit.
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || !first && '0' <= c && c <= '9'
+}
+
+// splitPipeline splits tokens into the stages between | operators.
+func splitPipeline(tokens []token) [][]token {
+	stages := [][]token{nil}
+	for _, t := range tokens {
+		if t == (token{text: "|", op: true}) {
+			stages = append(stages, nil)
+			continue
+		}
+		stages[len(stages)-1] = append(stages[len(stages)-1], t)
+	}
+	return stages
+}
+
+// redirections are the files a command reads its input from and writes its output to in place of the shell's.
+type redirections struct {
+	// stdin and stdout are nil when not redirected.
+	stdin  io.Reader
+	stdout io.Writer
+	files  []*os.File
+}
+
+// openRedirections opens the files of the < file, > file, >| file, and >> file redirections in tokens,
+// and reads stdin from the text of <<< word here-strings and << delimiter here-documents,
+// returning the expanded words left over. A later redirection replaces an earlier one.
+// With noclobber set, > fails rather than truncate an existing regular file, and >| is how to truncate it.
+func (s *shell) openRedirections(tokens []token) ([]string, *redirections, error) {
+	var (
+		r    = &redirections{}
+		rest = make([]string, 0, len(tokens))
+	)
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].op {
+			rest = append(rest, s.expandFields(tokens[i].text)...)
+			continue
+		}
+		op := tokens[i].text
+		if !isRedirection(op) {
+			_ = r.close()
+			return nil, nil, fmt.Errorf("%w: unexpected %s", ErrSyntax, op)
+		}
+		if i+1 == len(tokens) || tokens[i+1].op {
+			_ = r.close()
+			return nil, nil, fmt.Errorf("%w: %s expects a file", ErrSyntax, op)
+		}
+		i++
+		switch op {
+		case "<<<":
+			r.stdin = strings.NewReader(s.expandWord(tokens[i].text) + "\n")
+			continue
+		case "<<":
+			// a quoted delimiter leaves the body as it is.
+			body := tokens[i].body
+			if unquote(tokens[i].text) == tokens[i].text {
+				body = s.expandHereDoc(body)
+			}
+			r.stdin = strings.NewReader(body)
+			continue
+		}
+		target := s.expandWord(tokens[i].text)
+
+		var (
+			f   *os.File
+			err error
+		)
+		switch op {
+		case "<":
+			f, err = os.Open(target)
+		case ">":
+			if info, statErr := os.Stat(target); s.options.noclobber && statErr == nil && info.Mode().IsRegular() {
+				err = fmt.Errorf("%s: %w", target, ErrClobber)
+				break
+			}
+			f, err = os.Create(target)
+		case ">|":
+			f, err = os.Create(target)
+		case ">>":
+			f, err = os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+		}
+		if err != nil {
+			_ = r.close()
+			return nil, nil, err
+		}
+		r.files = append(r.files, f)
+		if op == "<" {
+			r.stdin = f
+		} else {
+			r.stdout = f
+		}
+	}
+
+	return rest, r, nil
+}
+
+// isRedirection reports whether op is an operator taking the word after it as a file, text, or here-document delimiter.
+func isRedirection(op string) bool {
+	switch op {
+	case "<", ">", ">|", ">>", "<<", "<<<":
+		return true
+	}
+	return false
+}
+
+// expandHereDoc expands the variables in the body of a here-document,
+// in which quotes are literal and a backslash only escapes a $ or \.
+func (s *shell) expandHereDoc(body string) string {
+	var b strings.Builder
+	for i := 0; i < len(body); {
+		switch {
+		case body[i] == '\\' && i+1 < len(body) && (body[i+1] == '$' || body[i+1] == '\\'):
+			b.WriteByte(body[i+1])
+			i += 2
+		case body[i] == '$':
+			value, next := s.expandVariable(body, i)
+			b.WriteString(value)
+			i = next
+		default:
+			b.WriteByte(body[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// close closes every redirected file, returning the first error.
+func (r *redirections) close() error {
+	var err error
+	for _, f := range r.files {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// executePipeline runs the external command of each stage at once, each reading the output of the one before,
+// with the last writing to w. A stage's own redirections take the place of the pipe on that side.
+// Like a shell without pipefail, only the last command's error is returned.
+func (s *shell) executePipeline(w io.Writer, stages ...[]token) (err error) {
+	var (
+		cmds      = make([]*exec.Cmd, len(stages))
+		redirects = make([]*redirections, 0, len(stages))
+	)
+	defer func() {
+		for _, r := range redirects {
+			if closeErr := r.close(); err == nil {
+				err = closeErr
+			}
+		}
+	}()
+	for i := range stages {
+		assignments, stage := s.splitAssignments(stages[i])
+		args, r, err := s.openRedirections(stage)
+		if err != nil {
+			return err
+		}
+		redirects = append(redirects, r)
+		if len(args) == 0 {
+			return fmt.Errorf("%w: empty command in pipeline", ErrSyntax)
+		}
+		if err := s.trace(assignments, args); err != nil {
+			return err
+		}
+		cmds[i] = exec.Command(args[0], args[1:]...)
+		cmds[i].Env = commandEnv(assignments)
+		cmds[i].Stderr = os.Stderr
+		cmds[i].Stdin = r.stdin
+		cmds[i].Stdout = r.stdout
+	}
+	for i := 0; i < len(cmds)-1; i++ {
+		if cmds[i].Stdout != nil {
+			continue
+		}
+		out, err := cmds[i].StdoutPipe()
+		if err != nil {
+			return err
+		}
+		if cmds[i+1].Stdin == nil {
+			cmds[i+1].Stdin = out
+		} else {
+			// nothing reads this output.
+			_ = out.Close()
+		}
+	}
+	if last := cmds[len(cmds)-1]; last.Stdout == nil {
+		last.Stdout = w
+	}
+
+	for i, cmd := range cmds {
+		if err := startCommand(cmd); err != nil {
+			// stop the commands already started rather than leave them blocked on the pipeline.
+			for _, started := range cmds[:i] {
+				_ = started.Process.Kill()
+				_ = started.Wait()
+			}
+			return err
+		}
+	}
+	defer s.foreground(cmds...)()
+	for _, cmd := range cmds {
+		err = cmd.Wait()
+	}
+	return err
+}
+
+// Implementations of the built-in commands:
+
+// environmentVariables prints the environment, or with a command after the arguments, runs it with that environment.
+// Leading KEY=VALUE arguments set variables in the environment, which starts out empty with -i and otherwise as the shell's.
+// The shell's own environment is never changed.
+func (s *shell) environmentVariables(w io.Writer, args ...string) error {
+	env := os.Environ()
+	if len(args) > 0 && args[0] == "-i" {
+		env = []string{}
+		args = args[1:]
+	}
+	for ; len(args) > 0 && strings.Contains(args[0], "="); args = args[1:] {
+		env = setEnv(env, args[0])
+	}
+	if len(args) > 0 {
+		return s.executeCommand(w, nil, env, args[0], args[1:]...)
+	}
+
+	for _, env := range env {
+		_, err := fmt.Fprintln(w, env)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setEnv sets the variable of the KEY=VALUE assignment in env, replacing the variable's earlier value if it has one.
+func setEnv(env []string, assignment string) []string {
+	key, _, _ := strings.Cut(assignment, "=")
+	for i := range env {
+		if strings.HasPrefix(env[i], key+"=") {
+			env[i] = assignment
+			return env
+		}
+	}
+	return append(env, assignment)
+}
+
+// echo prints args separated by spaces and followed by a newline.
+// Leading flags of n and e change that: -n leaves out the newline, and -e interprets the escapes described by echoEscapes.
+// Anything after the first argument that is not such a flag is printed as it is.
+func echo(w io.Writer, args ...string) error {
+	newline, escapes := true, false
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' && strings.Trim(args[0][1:], "ne") == "" {
+		newline = newline && !strings.Contains(args[0], "n")
+		escapes = escapes || strings.Contains(args[0], "e")
+		args = args[1:]
+	}
+	out := strings.Join(args, " ")
+	if escapes {
+		var stop bool
+		out, stop = echoEscapes(out)
+		newline = newline && !stop
+	}
+	if newline {
+		out += "\n"
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
+
+// echoEscapes replaces the backslash escapes in s: \\, \a, \b, \e, \f, \n, \r, \t, \v, and \0 followed by up to
+// three octal digits. \c drops the rest of the output, which is reported by stop. Other backslashes are kept.
+func echoEscapes(s string) (out string, stop bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case '\\':
+			b.WriteByte('\\')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'c':
+			return b.String(), true
+		case 'e':
+			b.WriteByte(0x1b)
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			var c byte
+			for n := 0; n < 3 && i+1 < len(s) && '0' <= s[i+1] && s[i+1] <= '7'; n++ {
+				i++
+				c = c*8 + s[i] - '0'
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), false
+}
+
+func printWorkingDirectory(w io.Writer) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, wd)
+	return err
+}
+
+// exitShell asks the loop to exit with the status in args, or with the last status when there is none.
+// Only the low 8 bits of the status are kept, as the OS only reports those.
+func (s *shell) exitShell(args ...string) error {
+	code := s.status
+	switch len(args) {
+	case 0:
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("exit: %s: numeric argument required", args[0])
+		}
+		code = n & 0xff
+	default:
+		return fmt.Errorf("%w: expected zero or one arguments (status)", builtins.ErrInvalidArgCount)
+	}
+	s.requestExit(code)
+	return nil
+}
+
+// requestExit asks the loop to exit with code, unless an exit has already been asked for.
+// The loop notices exiting itself, so a full exit channel is left as it is rather than waited on.
+func (s *shell) requestExit(code int) {
+	if s.exiting {
+		return
+	}
+	s.exiting, s.exitCode = true, code
+	select {
+	case s.exit <- struct{}{}:
+	default:
+	}
+}
+
+// alias defines each name=value argument as an alias, and prints the alias of each name argument.
+// With no arguments it prints every alias in name order.
+func (s *shell) alias(w io.Writer, args ...string) error {
+	if len(args) == 0 {
+		names := make([]string, 0, len(s.aliases))
+		for name := range s.aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		args = names
+	}
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if ok {
+			if name == "" || strings.ContainsAny(name, " \t\n'\"\\$|&;<>") {
+				return fmt.Errorf("alias: invalid name %q", name)
+			}
+			if s.aliases == nil {
+				s.aliases = make(map[string]string)
+			}
+			s.aliases[name] = value
+			continue
+		}
+		value, ok = s.aliases[name]
+		if !ok {
+			return fmt.Errorf("alias: %s not found", name)
+		}
+		// quote the value so the line can be entered again to define the alias.
+		if _, err := fmt.Fprintf(w, "alias %s='%s'\n", name, strings.ReplaceAll(value, "'", `'\''`)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unalias removes the alias of each name.
+func (s *shell) unalias(args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: expected at least one argument (name)", builtins.ErrInvalidArgCount)
+	}
+	for _, name := range args {
+		if _, ok := s.aliases[name]; !ok {
+			return fmt.Errorf("unalias: %s not found", name)
+		}
+		delete(s.aliases, name)
+	}
+	return nil
+}
+
+// read reads a line of input into the environment variables named in args, or into REPLY when none are.
+// The line is split on whitespace, with the last variable getting the rest of it and any past its end set empty.
+// A prompt given with -p is printed first, unless prompts are off.
+func (s *shell) read(w io.Writer, args ...string) error {
+	var prompt string
+	if len(args) > 0 && args[0] == "-p" {
+		if len(args) == 1 {
+			return fmt.Errorf("%w: -p expects a prompt", builtins.ErrInvalidArgCount)
+		}
+		prompt, args = args[1], args[2:]
+	}
+	if len(args) == 0 {
+		args = []string{"REPLY"}
+	}
+	for _, name := range args {
+		if !isName(name) {
+			return fmt.Errorf("read: invalid name %q", name)
+		}
+	}
+	if s.editor == nil && s.input == nil {
+		return errors.New("read: no input to read from")
+	}
+	if s.noPrompt {
+		prompt = ""
+	}
+
+	r := s.input
+	if s.stdin != nil {
+		r = s.stdin
+	}
+	line, err := s.readLine(r, w, prompt)
+	if line == "" && err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\n")
+	for i, name := range args {
+		line = strings.TrimLeft(line, " \t")
+		value := strings.TrimRight(line, " \t")
+		if i < len(args)-1 {
+			if end := strings.IndexAny(line, " \t"); end >= 0 {
+				value = line[:end]
+			}
+			line = line[len(value):]
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandAliases replaces the first word of each command in tokens with the tokens of its alias, if it has one.
+// The first word of an alias is expanded in turn, unless it is an alias already being expanded,
+// so an alias such as ls='ls -l' does not expand forever.
+func (s *shell) expandAliases(tokens []token) ([]token, error) {
+	if len(s.aliases) == 0 {
+		return tokens, nil
+	}
+	var (
+		expanded = make([]token, 0, len(tokens))
+		// commandStart is set when the next token starts a command.
+		commandStart = true
+	)
+	for _, t := range tokens {
+		if commandStart && !t.op {
+			aliased, err := s.expandAlias(t, nil)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, aliased...)
+		} else {
+			expanded = append(expanded, t)
+		}
+		commandStart = t.op && !isRedirection(t.text)
+	}
+	return expanded, nil
+}
+
+// expandAlias expands the alias of word, skipping the aliases in expanding.
+func (s *shell) expandAlias(word token, expanding map[string]bool) ([]token, error) {
+	value, ok := s.aliases[word.text]
+	if !ok || expanding[word.text] {
+		return []token{word}, nil
+	}
+	tokens, err := tokenize(value)
+	if err != nil {
+		return nil, fmt.Errorf("alias %s: %w", word.text, err)
+	}
+	if len(tokens) == 0 || tokens[0].op {
+		return tokens, nil
+	}
+	if expanding == nil {
+		expanding = make(map[string]bool)
+	}
+	expanding[word.text] = true
+	first, err := s.expandAlias(tokens[0], expanding)
+	if err != nil {
+		return nil, err
+	}
+	return append(first, tokens[1:]...), nil
+}
+
+// source runs each line of the file at args[0] as if it were entered, in this shell,
+// with the rest of args as the positional parameters while it runs.
+// A line that fails does not stop the rest of the file from running; the errors of all the lines are returned together.
+func (s *shell) source(w io.Writer, exit chan<- struct{}, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: expected at least one argument (file)", builtins.ErrInvalidArgCount)
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	params := s.params
+	s.params = args[1:]
+	defer func() { s.params = params }()
+
+	var (
+		errs    listErrors
+		scanner = bufio.NewScanner(f)
+	)
+	// line collects a command continued over several lines, which errors report from where it started.
+	var (
+		line  string
+		start int
+	)
+	for n := 1; scanner.Scan(); n++ {
+		if line == "" {
+			start = n
+		}
+		var more bool
+		if line, more = continuation(line + scanner.Text() + "\n"); more {
+			continue
+		}
+		if err := s.handleInput(w, strings.TrimSuffix(line, "\n"), exit); err != nil {
+			errs = append(errs, fmt.Errorf("%s:%d: %w", args[0], start, err))
+		}
+		line = ""
+		if s.exiting {
+			break // an exit in the file exits the shell, without running the rest of the file.
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	if line != "" {
+		errs = append(errs, fmt.Errorf("%s:%d: %w", args[0], start, errUnterminated))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// sourceRC sources the startup file, if there is one.
+func (s *shell) sourceRC(w io.Writer, exit chan<- struct{}) error {
+	if s.rcFile == "" {
+		return nil
+	}
+	if _, err := os.Stat(s.rcFile); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return s.source(w, exit, s.rcFile)
+}
+
+// showHistory prints the numbered history, or clears it given -c.
+func (s *shell) showHistory(w io.Writer, args ...string) error {
+	switch {
+	case len(args) == 0:
+		for i, line := range s.history {
+			if _, err := fmt.Fprintf(w, "%5d  %s\n", i+1, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case len(args) == 1 && args[0] == "-c":
+		s.history = nil
+		return nil
+	default:
+		return fmt.Errorf("%w: expected no arguments or -c", builtins.ErrInvalidArgCount)
+	}
+}
+
+// loadHistory reads the history saved by an earlier session, if any.
+func (s *shell) loadHistory() error {
+	if s.historyFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.historyFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			s.history = append(s.history, unescapeHistory(line))
+		}
+	}
+	return nil
+}
+
+// saveHistory writes the history for the next session to load.
+func (s *shell) saveHistory() error {
+	if s.historyFile == "" {
+		return nil
+	}
+	var data strings.Builder
+	for _, line := range s.history {
+		data.WriteString(escapeHistory(line) + "\n")
+	}
+	return os.WriteFile(s.historyFile, []byte(data.String()), 0o600)
+}
+
+// escapeHistory writes the newlines of a command continued over several lines, or of a here-document, as \n,
+// and each backslash as \\, so every command takes one line of the history file.
+func escapeHistory(line string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(line)
+}
+
+// unescapeHistory turns a line of the history file back into the command escapeHistory wrote.
+// A backslash before anything but another backslash or an n is kept as it is.
+func unescapeHistory(line string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(line)
 }
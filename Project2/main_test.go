@@ -1,10 +1,21 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/require"
 	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"testing/iotest"
 	"time"
@@ -30,10 +41,16 @@ func Test_runLoop(t *testing.T) {
 		},
 		{
 			name: "read error should have no effect",
+			args: args{
+				r: iotest.ErrReader(errors.New("read failed")),
+			},
+			wantErrW: "read failed",
+		},
+		{
+			name: "EOF should exit",
 			args: args{
 				r: iotest.ErrReader(io.EOF),
 			},
-			wantErrW: "EOF",
 		},
 	}
 	for _, tt := range tests {
@@ -45,7 +62,7 @@ func Test_runLoop(t *testing.T) {
 
 			exit := make(chan struct{}, 2)
 			// run the loop for 10ms
-			go runLoop(tt.args.r, w, errW, exit)
+			go (&shell{}).runLoop(tt.args.r, w, errW, exit)
 			time.Sleep(10 * time.Millisecond)
 			exit <- struct{}{}
 
@@ -58,3 +75,1739 @@ func Test_runLoop(t *testing.T) {
 		})
 	}
 }
+
+func Test_history(t *testing.T) {
+	t.Parallel()
+	historyFile := path.Join(t.TempDir(), historyFileName)
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "lists commands in order",
+			input: "echo one\n  echo two  \nhistory\nexit\n",
+			want:  "    1  echo one\n    2  echo two\n    3  history\n",
+		},
+		{
+			name:  "loads the previous session",
+			input: "history\nexit\n",
+			want: "    1  echo one\n    2  echo two\n    3  history\n    4  exit\n" +
+				"    5  history\n",
+		},
+		{
+			name:  "clear",
+			input: "history -c\nhistory\nexit\n",
+			want:  "$     1  history\n",
+		},
+	}
+	// the cases share the history file, so they run in order.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			errW := &bytes.Buffer{}
+			sh := &shell{historyFile: historyFile}
+			sh.runLoop(strings.NewReader(tt.input), w, errW, make(chan struct{}, 2))
+
+			require.Empty(t, errW.String())
+			require.Contains(t, w.String(), tt.want)
+			require.Equal(t, 1, strings.Count(w.String(), "    1  "))
+		})
+	}
+}
+
+func Test_saveHistoryRoundTrip(t *testing.T) {
+	t.Parallel()
+	historyFile := path.Join(t.TempDir(), historyFileName)
+	history := []string{
+		"echo one",
+		"echo \"x\ny\"",
+		"echo a \\\nb",
+		"cat <<EOF\nhi\nEOF",
+		`printf 'a\n' \\n`,
+	}
+	require.NoError(t, (&shell{historyFile: historyFile, history: history}).saveHistory())
+	data, err := os.ReadFile(historyFile)
+	require.NoError(t, err)
+	require.Equal(t, len(history), strings.Count(string(data), "\n"), "each command should take one line of the file")
+
+	sh := &shell{historyFile: historyFile}
+	require.NoError(t, sh.loadHistory())
+	require.Equal(t, history, sh.history)
+}
+
+func Test_executePipeline(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		input   string
+		wantW   string
+		wantErr bool
+	}{
+		{
+			name:  "two stages",
+			input: "echo hello | cat",
+			wantW: "hello\n",
+		},
+		{
+			name:  "three stages",
+			input: "printf 'b\\na\\nb\\n'|sort|uniq",
+			wantW: "a\nb\n",
+		},
+		{
+			name:    "empty stage",
+			input:   "echo hello |",
+			wantErr: true,
+		},
+		{
+			name:    "unknown command",
+			input:   "echo hello | no-such-command-gosh",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			err := (&shell{}).handleInput(w, tt.input, make(chan struct{}, 2))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantW, w.String())
+		})
+	}
+}
+
+func Test_redirections(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		file     string
+		inputs   []string
+		wantW    string
+		wantFile string
+		wantErr  bool
+	}{
+		{
+			name:     "output",
+			file:     "stale",
+			inputs:   []string{"echo hi > %s"},
+			wantFile: "hi\n",
+		},
+		{
+			name:     "output joined to the file",
+			inputs:   []string{"echo hi >%s"},
+			wantFile: "hi\n",
+		},
+		{
+			name:     "append",
+			file:     "first\n",
+			inputs:   []string{"echo second >> %s", "echo third >>%s"},
+			wantFile: "first\nsecond\nthird\n",
+		},
+		{
+			name:     "external command output",
+			inputs:   []string{"printf hi > %s"},
+			wantFile: "hi",
+		},
+		{
+			name:     "input",
+			file:     "from a file\n",
+			inputs:   []string{"cat < %s"},
+			wantW:    "from a file\n",
+			wantFile: "from a file\n",
+		},
+		{
+			name:     "pipeline ends",
+			file:     "b\na\n",
+			inputs:   []string{"cat < %[1]s | sort > %[1]s.sorted", "cat %[1]s.sorted"},
+			wantW:    "a\nb\n",
+			wantFile: "b\na\n",
+		},
+		{
+			name:    "missing input file",
+			inputs:  []string{"cat < %s"},
+			wantErr: true,
+		},
+		{
+			name:    "missing file name",
+			inputs:  []string{"echo hi >"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			file := path.Join(t.TempDir(), "file")
+			if tt.file != "" {
+				require.NoError(t, os.WriteFile(file, []byte(tt.file), 0o644))
+			}
+			w := &bytes.Buffer{}
+			sh := &shell{}
+			for _, input := range tt.inputs {
+				if strings.Contains(input, "%") {
+					input = fmt.Sprintf(input, file)
+				}
+				err := sh.handleInput(w, input, make(chan struct{}, 2))
+				require.Equal(t, tt.wantErr, err != nil, "handleInput() error = %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			require.Equal(t, tt.wantW, w.String())
+			got, err := os.ReadFile(file)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantFile, string(got))
+		})
+	}
+}
+
+func Test_noclobber(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		noclobber bool
+		file      string
+		input     string
+		wantFile  string
+		wantErr   error
+	}{
+		{name: "overwrite blocked", noclobber: true, file: "keep\n", input: "echo hi > %s", wantFile: "keep\n", wantErr: ErrClobber},
+		{name: "new file", noclobber: true, input: "echo hi > %s", wantFile: "hi\n"},
+		{name: "forced", noclobber: true, file: "stale\n", input: "echo hi >| %s", wantFile: "hi\n"},
+		{name: "append", noclobber: true, file: "first\n", input: "echo second >> %s", wantFile: "first\nsecond\n"},
+		{name: "forced without noclobber", file: "stale\n", input: "echo hi >|%s", wantFile: "hi\n"},
+		{name: "not a regular file", noclobber: true, input: "echo hi > /dev/null"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			file := path.Join(t.TempDir(), "file")
+			if tt.file != "" {
+				require.NoError(t, os.WriteFile(file, []byte(tt.file), 0o644))
+			}
+			input := tt.input
+			if strings.Contains(input, "%") {
+				input = fmt.Sprintf(input, file)
+			}
+			sh := &shell{options: options{noclobber: tt.noclobber}}
+			require.ErrorIs(t, sh.handleInput(io.Discard, input, make(chan struct{}, 2)), tt.wantErr)
+			if tt.wantFile != "" {
+				got, err := os.ReadFile(file)
+				require.NoError(t, err)
+				require.Equal(t, tt.wantFile, string(got))
+			}
+		})
+	}
+}
+
+func Test_backgroundJobs(t *testing.T) {
+	t.Parallel()
+	// a file rather than a buffer, so the jobs write to it directly instead of through a goroutine racing the test.
+	w, err := os.Create(path.Join(t.TempDir(), "out"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	output := func() string {
+		t.Helper()
+		got, err := os.ReadFile(w.Name())
+		require.NoError(t, err)
+		require.NoError(t, w.Truncate(0))
+		_, err = w.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		return string(got)
+	}
+	sh := &shell{}
+
+	start := time.Now()
+	require.NoError(t, sh.handleInput(w, "sleep 5 &", make(chan struct{}, 2)))
+	t.Cleanup(func() {
+		_ = sh.jobs[0].cmd.Process.Kill()
+		<-sh.jobs[0].done
+	})
+	require.Less(t, time.Since(start), time.Second, "the prompt should return before the job is done")
+	require.Regexp(t, `^\[1\] \d+\n$`, output())
+
+	require.NoError(t, sh.handleInput(w, "true&", make(chan struct{}, 2)))
+	require.Len(t, sh.jobs, 2)
+	<-sh.jobs[1].done
+	output()
+
+	sh.reportJobs(w)
+	require.Equal(t, "[2] Done\ttrue &\n", output())
+	require.Len(t, sh.jobs, 1, "the running job should still be tracked")
+}
+
+func Test_tokenize(t *testing.T) {
+	t.Setenv("GOSH_EXPAND", "value")
+	t.Setenv("GOSH_EXPAND_EMPTY", "")
+	t.Setenv("GOSH_EXPAND_SPACES", "two words")
+	t.Setenv("HOME", "/home/gosh")
+	words := func(texts ...string) []token {
+		tokens := make([]token, len(texts))
+		for i := range texts {
+			tokens[i] = token{text: texts[i]}
+		}
+		return tokens
+	}
+	tests := []struct {
+		name    string
+		in      string
+		want    []token
+		wantErr error
+	}{
+		{name: "words", in: "  echo a\tb  ", want: words("echo", "a", "b")},
+		{name: "empty", in: " \n"},
+		{name: "full-line comment", in: "# echo a"},
+		{name: "inline comment", in: "echo a # b c", want: words("echo", "a")},
+		{name: "comment after operator", in: "echo a;# b", want: append(words("echo", "a"), token{text: ";", op: true})},
+		{name: "hash inside a word", in: "echo a#b", want: words("echo", "a#b")},
+		{name: "quoted hash", in: `echo "#a" '#b' \#c`, want: words("echo", "#a", "#b", "#c")},
+		{name: "set variable", in: "$GOSH_EXPAND", want: words("value")},
+		{name: "braces", in: "${GOSH_EXPAND}s", want: words("values")},
+		{name: "surrounded", in: "a-$GOSH_EXPAND-b", want: words("a-value-b")},
+		{name: "unset variable", in: "[$GOSH_EXPAND_UNSET]", want: words("[]")},
+		{name: "empty variable", in: "[$GOSH_EXPAND_EMPTY]", want: words("[]")},
+		{name: "value is not split", in: "$GOSH_EXPAND_SPACES", want: words("two words")},
+		{name: "escaped dollar", in: "\\$GOSH_EXPAND", want: words("$GOSH_EXPAND")},
+		{name: "shell PID", in: "$$", want: words(strconv.Itoa(os.Getpid()))},
+		{name: "lone dollar", in: "5$ $", want: words("5$", "$")},
+		{name: "unterminated braces", in: "${GOSH_EXPAND", want: words("${GOSH_EXPAND")},
+		{name: "tilde", in: "~", want: words("/home/gosh")},
+		{name: "tilde path", in: "~/sub", want: words("/home/gosh/sub")},
+		{name: "tilde user", in: "~root/sub", want: words("/root/sub")},
+		{name: "tilde unknown user", in: "~no-such-user-gosh/sub", want: words("~no-such-user-gosh/sub")},
+		{name: "tilde mid-argument", in: "a~/sub --dir=~", want: words("a~/sub", "--dir=~")},
+		{name: "quoted tilde", in: `'~'/sub "~" \~`, want: words("~/sub", "~", "~")},
+		{name: "digit is a positional parameter", in: "$1a", want: words("a")},
+		{name: "name cannot start with a digit", in: "$0a", want: words("$0a")},
+		{name: "double quoted spaces", in: `echo "hello  world"`, want: words("echo", "hello  world")},
+		{name: "single quoted spaces", in: `echo 'hello  world'`, want: words("echo", "hello  world")},
+		{name: "adjacent segments", in: `a"b c"'d e'f`, want: words("ab cd ef")},
+		{name: "empty quotes", in: `echo "" ''`, want: words("echo", "", "")},
+		{name: "expanded in double quotes", in: `"$GOSH_EXPAND ${GOSH_EXPAND}"`, want: words("value value")},
+		{name: "literal in single quotes", in: `'$GOSH_EXPAND \"'`, want: words(`$GOSH_EXPAND \"`)},
+		{name: "escaped quotes", in: `\"a\' "b\"c\\"`, want: words(`"a'`, `b"c\`)},
+		{name: "escaped space", in: `a\ b`, want: words("a b")},
+		{name: "other escapes kept in double quotes", in: `"a\nb"`, want: words(`a\nb`)},
+		{
+			name: "operators",
+			in:   "cat<in|sort >>out&",
+			want: []token{
+				{text: "cat"}, {text: "<", op: true}, {text: "in"}, {text: "|", op: true},
+				{text: "sort"}, {text: ">>", op: true}, {text: "out"}, {text: "&", op: true},
+			},
+		},
+		{name: "quoted operators", in: `echo "|" '>' \&`, want: words("echo", "|", ">", "&")},
+		{name: "unterminated double quote", in: `echo "a`, wantErr: ErrSyntax},
+		{name: "unterminated single quote", in: `echo 'a`, wantErr: ErrSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenize(tt.in)
+			require.ErrorIs(t, err, tt.wantErr)
+			// compare the words as they are once expanded.
+			sh := &shell{}
+			for i := range got {
+				if !got[i].op {
+					got[i].text = sh.expandWord(got[i].text)
+				}
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_handleInputExpandsVariables(t *testing.T) {
+	t.Setenv("GOSH_EXPAND", "value")
+	w := &bytes.Buffer{}
+	sh := &shell{}
+	require.NoError(t, sh.handleInput(w, "echo $GOSH_EXPAND \\$GOSH_EXPAND", make(chan struct{}, 2)))
+	require.NoError(t, sh.handleInput(w, "printf '%s\\n' ${GOSH_EXPAND}", make(chan struct{}, 2)))
+	require.Equal(t, "value $GOSH_EXPAND\nvalue\n", w.String())
+}
+
+func Test_handleInputLists(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		input   string
+		wantW   string
+		wantErr bool
+	}{
+		{name: "sequence", input: "echo a; echo b;", wantW: "a\nb\n"},
+		{name: "sequence runs after a failure", input: "false; echo b", wantW: "b\n", wantErr: true},
+		{name: "and after success", input: "true && echo yes", wantW: "yes\n"},
+		{name: "and after failure", input: "false && echo yes", wantErr: true},
+		{name: "or after success", input: "true || echo no"},
+		{name: "or after failure", input: "false || echo fallback", wantW: "fallback\n", wantErr: true},
+		{name: "or after skipped and", input: "false && echo a || echo b", wantW: "b\n", wantErr: true},
+		{name: "and after skipped or", input: "true || echo a && echo b", wantW: "b\n"},
+		{name: "builtin failure", input: "cd /no/such/dir && echo moved", wantErr: true},
+		{name: "quoted operators", input: `echo "a;b" 'c&&d'`, wantW: "a;b c&&d\n"},
+		{name: "comment", input: "# echo a"},
+		{name: "inline comment", input: "echo a # ; echo b", wantW: "a\n"},
+		{name: "leading operator", input: "; echo a", wantErr: true},
+		{name: "trailing and", input: "echo a &&", wantErr: true},
+		{name: "doubled operator", input: "echo a && || echo b", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			err := (&shell{errW: io.Discard}).handleInput(w, tt.input, make(chan struct{}, 2))
+			require.Equal(t, tt.wantErr, err != nil, "handleInput() error = %v", err)
+			require.Equal(t, tt.wantW, w.String())
+		})
+	}
+}
+
+func Test_exitStatus(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		inputs []string
+		want   string
+	}{
+		{name: "initially zero", inputs: []string{"echo $?"}, want: "0\n"},
+		{name: "success", inputs: []string{"false; true; echo $?"}, want: "0\n"},
+		{name: "failure", inputs: []string{"false", "echo $?"}, want: "1\n"},
+		{name: "exit code", inputs: []string{"sh -c 'exit 3'; echo ${?}"}, want: "3\n"},
+		{name: "builtin failure", inputs: []string{"cd /no/such/dir; echo $?"}, want: "1\n"},
+		{name: "builtin usage", inputs: []string{"cd a b; echo $?"}, want: "2\n"},
+		{name: "syntax error", inputs: []string{"echo 'a", "echo $?"}, want: "2\n"},
+		{name: "expanded when run", inputs: []string{"true; echo $?; false; echo \"$?\""}, want: "0\n1\n"},
+		{name: "single quoted", inputs: []string{"false; echo '$?'"}, want: "$?\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			sh := &shell{errW: io.Discard}
+			for _, input := range tt.inputs {
+				_ = sh.handleInput(w, input, make(chan struct{}, 2))
+			}
+			require.Equal(t, tt.want, w.String())
+		})
+	}
+}
+
+// waitForeground waits until the shell has as many commands running in the foreground as want.
+func waitForeground(t *testing.T, sh *shell, want int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		sh.mu.Lock()
+		defer sh.mu.Unlock()
+		return len(sh.running) == want
+	}, 2*time.Second, time.Millisecond)
+}
+
+func Test_interrupt(t *testing.T) {
+	t.Parallel()
+	t.Run("running command", func(t *testing.T) {
+		t.Parallel()
+		sh := &shell{}
+		done := make(chan error)
+		go func() {
+			done <- sh.handleInput(io.Discard, "sleep 5", make(chan struct{}, 2))
+		}()
+		waitForeground(t, sh, 1)
+
+		sh.interrupt(io.Discard)
+		select {
+		case err := <-done:
+			require.Error(t, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("the interrupted command is still running")
+		}
+		require.Equal(t, 128+int(syscall.SIGINT), sh.status)
+	})
+	t.Run("no command", func(t *testing.T) {
+		t.Parallel()
+		w := &bytes.Buffer{}
+		(&shell{reading: true}).interrupt(w)
+		require.Regexp(t, `^\n.* \$ $`, w.String())
+	})
+	t.Run("no prompts", func(t *testing.T) {
+		t.Parallel()
+		w := &bytes.Buffer{}
+		(&shell{reading: true, noPrompt: true}).interrupt(w)
+		require.Empty(t, w.String(), "a script or piped input should get no stray prompt")
+	})
+	t.Run("not reading", func(t *testing.T) {
+		t.Parallel()
+		w := &bytes.Buffer{}
+		(&shell{}).interrupt(w)
+		require.Empty(t, w.String(), "only the loop writes while it is running a builtin")
+	})
+}
+
+// syncBuffer is a bytes.Buffer safe to write from the shell's interrupt handler while the shell also writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Test_runLoopInterrupt is not parallel because it interrupts the whole test process,
+// which only the shell's handler may be listening for.
+func Test_runLoopInterrupt(t *testing.T) {
+	r, input := io.Pipe()
+	w := &syncBuffer{}
+	sh := &shell{}
+	done := make(chan struct{})
+	go func() {
+		sh.runLoop(r, w, w, make(chan struct{}, 2))
+		close(done)
+	}()
+
+	start := time.Now()
+	_, err := io.WriteString(input, "sleep 5\n")
+	require.NoError(t, err)
+	waitForeground(t, sh, 1)
+
+	self, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, self.Signal(os.Interrupt))
+	waitForeground(t, sh, 0)
+
+	_, err = io.WriteString(input, "exit\n")
+	require.NoError(t, err)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the shell did not keep running after the interrupt")
+	}
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func Test_runLoopEOF(t *testing.T) {
+	t.Parallel()
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	done := make(chan struct{})
+	go func() {
+		// no exit is ever sent, so only the end of the input can stop the loop.
+		(&shell{}).runLoop(strings.NewReader("echo one\necho two"), w, errW, make(chan struct{}, 2))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("runLoop() kept looping at the end of the input")
+	}
+	require.Empty(t, errW.String())
+	require.Contains(t, w.String(), "one\n")
+	require.Contains(t, w.String(), "two\n")
+	require.True(t, strings.HasSuffix(w.String(), "$ two\n\nexiting gracefully...\n"), w.String())
+}
+
+func Test_glob(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.go"} {
+		require.NoError(t, os.WriteFile(path.Join(dir, name), nil, 0o644))
+	}
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "star", input: "echo DIR/*.txt", want: "DIR/a.txt DIR/b.txt"},
+		{name: "question mark", input: "echo DIR/?.go", want: "DIR/c.go"},
+		{name: "class", input: "echo DIR/[ab].txt", want: "DIR/a.txt DIR/b.txt"},
+		{name: "quoted prefix", input: "echo 'DIR'/*.go", want: "DIR/c.go"},
+		{name: "no match", input: "echo DIR/*.md", want: "DIR/*.md"},
+		{name: "double quoted", input: `echo "DIR/*.txt"`, want: "DIR/*.txt"},
+		{name: "single quoted", input: `echo 'DIR/*.txt'`, want: "DIR/*.txt"},
+		{name: "escaped", input: `echo DIR/\*.txt`, want: "DIR/*.txt"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			require.NoError(t, (&shell{}).handleInput(w, strings.ReplaceAll(tt.input, "DIR", dir), make(chan struct{}, 2)))
+			require.Equal(t, strings.ReplaceAll(tt.want, "DIR", dir)+"\n", w.String())
+		})
+	}
+}
+
+func Test_alias(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		inputs  []string
+		want    string
+		wantErr bool
+	}{
+		{name: "define and use", inputs: []string{"alias greet='echo hello'", "greet world"}, want: "hello world\n"},
+		{name: "after operators", inputs: []string{"alias say=echo", "say a; say b && say c | cat"}, want: "a\nb\nc\n"},
+		{name: "only the command word", inputs: []string{"alias a=echo", "a a"}, want: "a\n"},
+		{name: "quoted word", inputs: []string{"alias greet='echo hello'", "'greet'"}, wantErr: true},
+		{name: "list", inputs: []string{"alias b='echo b' a=ls", "alias"}, want: "alias a='ls'\nalias b='echo b'\n"},
+		{name: "print one", inputs: []string{"alias q=\"echo 'x'\"", "alias q"}, want: "alias q='echo '\\''x'\\'''\n"},
+		{name: "chained", inputs: []string{"alias a=b b='echo chained'", "a"}, want: "chained\n"},
+		{name: "recursive", inputs: []string{"alias echo='echo again'", "echo x"}, want: "again x\n"},
+		{name: "loop", inputs: []string{"alias a=b b=a", "a"}, wantErr: true},
+		{name: "unknown", inputs: []string{"alias missing"}, wantErr: true},
+		{name: "unalias", inputs: []string{"alias greet='echo hello'", "unalias greet", "greet"}, wantErr: true},
+		{name: "unalias unknown", inputs: []string{"unalias missing"}, wantErr: true},
+		{name: "unalias without args", inputs: []string{"unalias"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			sh := &shell{}
+			var err error
+			for _, input := range tt.inputs {
+				err = sh.handleInput(w, input, make(chan struct{}, 2))
+			}
+			require.Equal(t, tt.wantErr, err != nil, "handleInput() error = %v", err)
+			require.Equal(t, tt.want, w.String())
+		})
+	}
+}
+
+func Test_source(t *testing.T) {
+	// not parallel: the sourced file sets an environment variable.
+	t.Setenv("GOSH_SOURCED", "")
+	file := path.Join(t.TempDir(), "setup.sh")
+	script := "# setup\nexport GOSH_SOURCED=yes\nalias greet='echo hello'\ncd /no/such/dir\ngreet $GOSH_SOURCED\n"
+	require.NoError(t, os.WriteFile(file, []byte(script), 0o600))
+
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	sh := &shell{errW: errW}
+	err := sh.handleInput(w, "source "+file, make(chan struct{}, 2))
+	require.ErrorContains(t, err, file+":4:", "the failing line should be reported")
+	require.Equal(t, "cd: chdir /no/such/dir: no such file or directory\n", errW.String())
+	require.Equal(t, "hello yes\n", w.String(), "the lines after a failure should still run")
+	require.Equal(t, "yes", os.Getenv("GOSH_SOURCED"))
+
+	w.Reset()
+	require.NoError(t, sh.handleInput(w, "greet again", make(chan struct{}, 2)), "aliases from the file should be kept")
+	require.Equal(t, "hello again\n", w.String())
+
+	w.Reset()
+	require.Error(t, sh.handleInput(w, ". "+file+".missing", make(chan struct{}, 2)))
+	require.Error(t, sh.handleInput(w, "source", make(chan struct{}, 2)))
+}
+
+func Test_sourceParams(t *testing.T) {
+	t.Parallel()
+	file := path.Join(t.TempDir(), "params.sh")
+	require.NoError(t, os.WriteFile(file, []byte("echo \"$1\" $# ${2}x$9.\n"), 0o600))
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "arguments", input: "source " + file + " 'one arg' two", want: "one arg 2 twox.\n"},
+		{name: "no arguments", input: ". " + file, want: " 0 x.\n"},
+		{name: "outside a script", input: "echo $# [$1] '$1'", want: "0 [] $1\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			sh := &shell{}
+			require.NoError(t, sh.handleInput(w, tt.input, make(chan struct{}, 2)))
+			require.Equal(t, tt.want, w.String())
+			require.Nil(t, sh.params, "the arguments should only last while the script runs")
+		})
+	}
+}
+
+func Test_newShellRC(t *testing.T) {
+	t.Parallel()
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(home, rcFileName), []byte("alias greet='echo hello'\n"), 0o600))
+	tests := []struct {
+		name    string
+		home    string
+		rc      bool
+		want    string
+		wantErr bool
+	}{
+		{name: "rc file", home: home, rc: true, want: "hello rc\n"},
+		{name: "norc", home: home, wantErr: true},
+		{name: "no rc file", home: t.TempDir(), rc: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			errW := &bytes.Buffer{}
+			newShell(tt.home, tt.rc).runLoop(strings.NewReader("greet rc\n"), w, errW, make(chan struct{}, 2))
+			require.Equal(t, tt.wantErr, errW.Len() > 0, "runLoop() errors = %s", errW)
+			if tt.want != "" {
+				require.Contains(t, w.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_printPrompt(t *testing.T) {
+	// not parallel: the prompt is read from the environment.
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	u, err := user.Current()
+	require.NoError(t, err)
+	host, err := os.Hostname()
+	require.NoError(t, err)
+	symbol := "$"
+	if os.Geteuid() == 0 {
+		symbol = "#"
+	}
+	tests := []struct {
+		name  string
+		ps1   *string
+		wantW string
+	}{
+		{name: "unset", wantW: wd + " [" + u.Username + "] $ "},
+		{name: "empty", ps1: new(string)},
+		{name: "escapes", ps1: ptr(`\u@\h:\w\$ `), wantW: u.Username + "@" + host + ":" + wd + symbol + " "},
+		{name: "literal backslashes", ps1: ptr(`\\ \x \`), wantW: `\ \x \`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PS1", "")
+			if tt.ps1 == nil {
+				require.NoError(t, os.Unsetenv("PS1"))
+			} else {
+				require.NoError(t, os.Setenv("PS1", *tt.ps1))
+			}
+			w := &bytes.Buffer{}
+			require.NoError(t, printPrompt(w))
+			require.Equal(t, tt.wantW, w.String())
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func Test_which(t *testing.T) {
+	t.Parallel()
+	shPath, err := exec.LookPath("sh")
+	require.NoError(t, err)
+	tests := []struct {
+		name    string
+		input   string
+		wantW   string
+		wantErr bool
+	}{
+		{name: "executable", input: "which sh", wantW: shPath + "\n"},
+		{name: "builtin", input: "which cd", wantW: "cd: shell built-in command\n"},
+		{name: "several", input: "which echo sh", wantW: "echo: shell built-in command\n" + shPath + "\n"},
+		{name: "not found", input: "which no-such-command-gosh", wantErr: true},
+		{name: "some not found", input: "which no-such-command-gosh sh", wantW: shPath + "\n", wantErr: true},
+		{name: "no names", input: "which", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			sh := &shell{}
+			err := sh.handleInput(w, tt.input, make(chan struct{}, 2))
+			require.Equal(t, tt.wantErr, err != nil, "handleInput() error = %v", err)
+			require.Equal(t, tt.wantW, w.String())
+			if tt.wantErr {
+				require.Equal(t, 1, sh.status)
+			}
+		})
+	}
+}
+
+func Test_typeOf(t *testing.T) {
+	t.Parallel()
+	shPath, err := exec.LookPath("sh")
+	require.NoError(t, err)
+	tests := []struct {
+		name    string
+		inputs  []string
+		wantW   string
+		wantErr bool
+	}{
+		{name: "builtin", inputs: []string{"type cd"}, wantW: "cd is a shell builtin\n"},
+		{name: "alias", inputs: []string{"alias ll='ls -l'", "type ll"}, wantW: "ll is aliased to `ls -l'\n"},
+		{name: "builtin before alias", inputs: []string{"alias cd=ls", "type cd"}, wantW: "cd is a shell builtin\n"},
+		{name: "executable", inputs: []string{"type sh"}, wantW: "sh is " + shPath + "\n"},
+		{name: "not found", inputs: []string{"type no-such-command-gosh echo"}, wantW: "echo is a shell builtin\n", wantErr: true},
+		{name: "no names", inputs: []string{"type"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			sh := &shell{}
+			var err error
+			for _, input := range tt.inputs {
+				err = sh.handleInput(w, input, make(chan struct{}, 2))
+			}
+			require.Equal(t, tt.wantErr, err != nil, "handleInput() error = %v", err)
+			require.Equal(t, tt.wantW, w.String())
+		})
+	}
+}
+
+func Test_commandNotFound(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "command", input: "no-such-command-gosh a"},
+		{name: "pipeline", input: "echo a | no-such-command-gosh"},
+		{name: "background", input: "no-such-command-gosh &"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sh := &shell{}
+			err := sh.handleInput(io.Discard, tt.input, make(chan struct{}, 2))
+			require.ErrorIs(t, err, ErrCommandNotFound)
+			require.EqualError(t, err, "gosh: command not found: no-such-command-gosh")
+			require.Equal(t, 127, sh.status)
+		})
+	}
+
+	sh := &shell{}
+	err := sh.handleInput(io.Discard, "/no/such/dir/cmd", make(chan struct{}, 2))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrCommandNotFound, "other errors should keep their details")
+}
+
+func Test_registerBuiltin(t *testing.T) {
+	// not parallel: the builtins are shared by every shell.
+	t.Cleanup(func() { delete(builtinCommands, "gosh-dummy") })
+	var got []string
+	registerBuiltin("gosh-dummy", "gosh-dummy [arg ...]", "Print that it ran.", func(s *shell, w, _ io.Writer, args []string) error {
+		got = args
+		_, err := fmt.Fprintln(w, "dummy ran")
+		return err
+	})
+	require.True(t, isBuiltin("gosh-dummy"))
+
+	w := &bytes.Buffer{}
+	require.NoError(t, (&shell{}).handleInput(w, "gosh-dummy a 'b c' > /dev/null; gosh-dummy x", make(chan struct{}, 2)))
+	require.Equal(t, "dummy ran\n", w.String(), "the output should go to the redirection, then to the shell")
+	require.Equal(t, []string{"x"}, got)
+
+	w.Reset()
+	require.NoError(t, (&shell{}).handleInput(w, "type gosh-dummy", make(chan struct{}, 2)))
+	require.Equal(t, "gosh-dummy is a shell builtin\n", w.String())
+}
+
+func Test_builtinDiagnostics(t *testing.T) {
+	// not parallel: the builtins are shared by every shell.
+	t.Cleanup(func() { delete(builtinCommands, "gosh-warn") })
+	registerBuiltin("gosh-warn", "gosh-warn", "Print output and a warning.", func(s *shell, w, errW io.Writer, args []string) error {
+		if _, err := fmt.Fprintln(errW, "gosh-warn: careful"); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintln(w, "output")
+		return err
+	})
+
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	sh := &shell{noPrompt: true}
+	sh.runLoop(strings.NewReader("gosh-warn\ngosh-warn > /dev/null\n"), w, errW, make(chan struct{}, 2))
+	require.Equal(t, "output\n", w.String())
+	require.Equal(t, "gosh-warn: careful\ngosh-warn: careful\n", errW.String(), "the warnings should not follow the redirection")
+}
+
+func Test_builtinFailuresReportedOnce(t *testing.T) {
+	t.Parallel()
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	sh := &shell{noPrompt: true}
+	sh.runLoop(strings.NewReader("cd /no/such/dir\npath add\nkill abc; fg\necho $?\n"), w, errW, make(chan struct{}, 2))
+	require.Equal(t, "1\n", w.String(), "only the output should go to w")
+	require.Equal(t, "cd: chdir /no/such/dir: no such file or directory\n"+
+		"path: invalid argument count: expected add, prepend, or remove and a directory\nusage: path [add | prepend | remove dir]\n"+
+		"kill: abc: arguments must be process or job IDs\n"+
+		"fg: no current job\n", errW.String(), "each builtin should report its own failure, and only once")
+}
+
+func Test_jobControl(t *testing.T) {
+	t.Parallel()
+	// a file rather than a buffer, so the jobs write to it directly instead of through a goroutine racing the test.
+	w, err := os.Create(path.Join(t.TempDir(), "out"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Close() })
+	output := func() string {
+		t.Helper()
+		got, err := os.ReadFile(w.Name())
+		require.NoError(t, err)
+		require.NoError(t, w.Truncate(0))
+		_, err = w.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		return string(got)
+	}
+	errW := &bytes.Buffer{}
+	sh := &shell{errW: errW}
+	run := func(input string) error {
+		return sh.handleInput(w, input, make(chan struct{}, 2))
+	}
+
+	require.NoError(t, run("sleep 5 &"))
+	sleeper := sh.jobs[0]
+	t.Cleanup(func() {
+		_ = sleeper.cmd.Process.Kill()
+		<-sleeper.done
+	})
+	output()
+	require.NoError(t, run("jobs"))
+	require.Regexp(t, `^\[1\] \d+ Running\tsleep 5 &\n$`, output())
+
+	require.NoError(t, run("kill -STOP %1"))
+	require.NoError(t, run("jobs"))
+	require.Regexp(t, `^\[1\] \d+ Stopped\tsleep 5 &\n$`, output())
+	require.NoError(t, run("bg %1"))
+	require.Equal(t, "[1] sleep 5 &\n", output())
+	require.Error(t, run("bg %1"), "a running job cannot be continued")
+	require.Equal(t, "bg: job 1 is already running\n", errW.String())
+
+	require.NoError(t, run("sh -c 'exit 3' &"))
+	output()
+	require.Error(t, run("fg"), "fg should return the exit of the most recent job")
+	require.Equal(t, "sh -c exit 3\n", output())
+	require.Equal(t, 3, sh.status)
+	require.Len(t, sh.jobs, 1, "the job brought to the foreground should be forgotten")
+
+	errW.Reset()
+	require.Error(t, run("fg %7"))
+	require.Error(t, run("fg 1 2"))
+	require.Error(t, run("jobs -l"))
+	require.Equal(t, "fg: %7: no such job\n"+
+		"fg: invalid argument count: expected zero or one arguments (job)\nusage: fg [%job]\n"+
+		"jobs: invalid argument count: expected zero arguments\nusage: jobs\n", errW.String())
+	require.Equal(t, 2, sh.status)
+}
+
+func Test_kill(t *testing.T) {
+	t.Parallel()
+	errW := &bytes.Buffer{}
+	sh := &shell{errW: errW}
+	run := func(input string) error {
+		return sh.handleInput(io.Discard, input, make(chan struct{}, 2))
+	}
+	start := func() *job {
+		t.Helper()
+		require.NoError(t, run("sleep 5 &"))
+		j := sh.jobs[len(sh.jobs)-1]
+		t.Cleanup(func() {
+			_ = j.cmd.Process.Kill()
+			<-j.done
+		})
+		return j
+	}
+	waitDone := func(j *job) {
+		t.Helper()
+		select {
+		case <-j.done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("the job was not killed")
+		}
+	}
+
+	term := start()
+	require.NoError(t, run("kill %1"))
+	waitDone(term)
+	require.Equal(t, "Exit (signal: terminated)", term.state())
+
+	killed := start()
+	pid := strconv.Itoa(killed.cmd.Process.Pid)
+	require.NoError(t, run("kill -STOP "+pid))
+	require.Equal(t, "Stopped", killed.state())
+	require.NoError(t, run("kill -SIGCONT %2"))
+	require.Equal(t, "Running", killed.state())
+	require.NoError(t, run("kill -9 "+pid))
+	waitDone(killed)
+	require.Equal(t, "Exit (signal: killed)", killed.state())
+
+	require.Empty(t, errW.String())
+
+	tests := []struct {
+		name, input, wantErrW string
+		wantStatus            int
+	}{
+		{name: "unknown job", input: "kill %9", wantErrW: "kill: %9: no such job\n", wantStatus: 1},
+		{name: "unknown PID", input: "kill 999999999", wantErrW: "kill: 999999999: os: process already finished\n", wantStatus: 1},
+		{name: "unknown signal", input: "kill -BOGUS " + pid, wantErrW: "kill: BOGUS: invalid signal\n", wantStatus: 1},
+		{
+			name:       "no targets",
+			input:      "kill -9",
+			wantErrW:   "kill: invalid argument count: expected at least one argument (pid or job)\nusage: kill [-signal] pid | %job ...\n",
+			wantStatus: 2,
+		},
+		{name: "not a PID", input: "kill abc %9", wantErrW: "kill: abc: arguments must be process or job IDs\nkill: %9: no such job\n", wantStatus: 1},
+	}
+	for _, tt := range tests {
+		errW.Reset()
+		require.Error(t, run(tt.input), tt.name)
+		require.Equal(t, tt.wantErrW, errW.String(), tt.name)
+		require.Equal(t, tt.wantStatus, sh.status, tt.name)
+	}
+}
+
+func Test_echo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "plain", args: []string{"a", "b"}, want: "a b\n"},
+		{name: "no args", want: "\n"},
+		{name: "no newline", args: []string{"-n", "x"}, want: "x"},
+		{name: "escapes", args: []string{"-e", `a\tb`}, want: "a\tb\n"},
+		{name: "escapes are literal by default", args: []string{`a\tb`}, want: "a\\tb\n"},
+		{name: "combined flags", args: []string{"-ne", `a\n`}, want: "a\n"},
+		{name: "separate flags", args: []string{"-n", "-e", `\x41`}, want: `\x41`},
+		{name: "octal", args: []string{"-e", `\0101\0`}, want: "A\x00\n"},
+		{name: "stop output", args: []string{"-e", `a\cb`, "c"}, want: "a"},
+		{name: "flags only before operands", args: []string{"x", "-n"}, want: "x -n\n"},
+		{name: "not a flag", args: []string{"-nx", "-"}, want: "-nx -\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			require.NoError(t, echo(w, tt.args...))
+			require.Equal(t, tt.want, w.String())
+		})
+	}
+}
+
+func Test_environmentVariables(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		input   string
+		wantW   string
+		wantErr bool
+	}{
+		{name: "command", input: "env GOSH_ENV_X=1 printenv GOSH_ENV_X", wantW: "1\n"},
+		{name: "later assignments win", input: "env GOSH_ENV_X=1 GOSH_ENV_X=2 printenv GOSH_ENV_X", wantW: "2\n"},
+		{name: "empty environment", input: "env -i GOSH_ENV_X=1 sh -c 'echo $GOSH_ENV_X $HOME'", wantW: "1\n"},
+		{name: "print", input: "env -i GOSH_ENV_X=1 GOSH_ENV_Y=2", wantW: "GOSH_ENV_X=1\nGOSH_ENV_Y=2\n"},
+		{name: "print nothing", input: "env -i"},
+		{name: "command fails", input: "env GOSH_ENV_X=1 printenv GOSH_ENV_UNSET", wantErr: true},
+		{name: "command not found", input: "env GOSH_ENV_X=1 no-such-command-gosh", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			err := (&shell{}).handleInput(w, tt.input, make(chan struct{}, 2))
+			require.Equal(t, tt.wantErr, err != nil, "handleInput() error = %v", err)
+			require.Equal(t, tt.wantW, w.String())
+			_, ok := os.LookupEnv("GOSH_ENV_X")
+			require.False(t, ok, "the shell's environment should not change")
+		})
+	}
+}
+
+func Test_runLoopBlankLines(t *testing.T) {
+	t.Parallel()
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	sh := &shell{}
+	sh.runLoop(strings.NewReader("\n   \n\t\nexit\n"), w, errW, make(chan struct{}, 2))
+
+	require.Empty(t, errW.String())
+	require.Equal(t, 4, strings.Count(w.String(), "$ "), "each line should get a fresh prompt")
+	require.Equal(t, []string{"exit"}, sh.history, "blank lines should not be kept")
+	require.Zero(t, sh.status)
+}
+
+func Test_exitShell(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    string
+		want     int
+		wantW    string
+		wantErrW string
+	}{
+		{name: "no status", input: "exit\n"},
+		{name: "status", input: "exit 2\necho not run\n", want: 2},
+		{name: "last status", input: "false\nexit\n", want: 1, wantErrW: "exit status 1"},
+		{name: "low 8 bits", input: "exit 258\n", want: 2},
+		{name: "not a number", input: "exit abc\necho still running\n", wantW: "still running\n", wantErrW: "numeric argument required"},
+		{name: "too many arguments", input: "exit 1 2\necho still running\n", wantW: "still running\n", wantErrW: "expected zero or one"},
+		{name: "end of input", input: "sh -c 'exit 5'", want: 5, wantErrW: "exit status 5"},
+		{name: "end of input after exit", input: "exit 3", want: 3},
+		{name: "rest of the list", input: "exit 2; echo not run\n", want: 2},
+		{name: "several in a list", input: "exit 1; exit 2; exit 3\n", want: 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			errW := &bytes.Buffer{}
+			got := (&shell{}).runLoop(strings.NewReader(tt.input), w, errW, make(chan struct{}, 2))
+			require.Equal(t, tt.want, got)
+			require.Contains(t, w.String(), tt.wantW)
+			require.NotContains(t, w.String(), "not run")
+			if tt.wantErrW != "" {
+				require.Contains(t, errW.String(), tt.wantErrW)
+			} else {
+				require.Empty(t, errW.String())
+			}
+		})
+	}
+}
+
+func Test_sourceExit(t *testing.T) {
+	t.Parallel()
+	file := path.Join(t.TempDir(), "exit.sh")
+	require.NoError(t, os.WriteFile(file, []byte("echo before\nexit 4; echo not run\necho not run\n"), 0o600))
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	got := (&shell{noPrompt: true}).runLoop(strings.NewReader("source "+file+"; echo not run\necho not run\n"), w, errW, make(chan struct{}, 2))
+	require.Equal(t, 4, got)
+	require.Equal(t, "before\n", w.String())
+	require.Empty(t, errW.String())
+}
+
+func Test_continuation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		line     string
+		want     string
+		wantMore bool
+	}{
+		{name: "complete", line: "echo a\n", want: "echo a\n"},
+		{name: "backslash", line: "echo a \\\n", want: "echo a ", wantMore: true},
+		{name: "backslash in a word", line: "echo a\\\n", want: "echo a", wantMore: true},
+		{name: "escaped backslash", line: "echo a\\\\\n", want: "echo a\\\\\n"},
+		{name: "quoted backslash", line: "echo 'a\\'\n", want: "echo 'a\\'\n"},
+		{name: "backslash in a comment", line: "echo a # b\\\n", want: "echo a # b\\\n"},
+		{name: "open single quote", line: "echo 'a\n", want: "echo 'a\n", wantMore: true},
+		{name: "open double quote", line: "echo \"a\n", want: "echo \"a\n", wantMore: true},
+		{name: "blank", line: "\n", want: "\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, more := continuation(tt.line)
+			require.Equal(t, tt.wantMore, more)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_runLoopContinuation(t *testing.T) {
+	t.Parallel()
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	sh := &shell{}
+	sh.runLoop(strings.NewReader("echo one \\\ntwo\\\n three\necho 'a\nb'\n"), w, errW, make(chan struct{}, 2))
+
+	require.Empty(t, errW.String())
+	require.Contains(t, w.String(), "$ > > one two three\n")
+	require.Contains(t, w.String(), "$ > a\nb\n")
+	require.Equal(t, []string{"echo one two three", "echo 'a\nb'"}, sh.history)
+}
+
+func Test_exportPath(t *testing.T) {
+	// not parallel: the test changes PATH, which every command is looked up in.
+	t.Setenv("PATH", os.Getenv("PATH"))
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho from $0\n"
+	require.NoError(t, os.WriteFile(path.Join(dir, "gosh-path-script"), []byte(script), 0o755))
+
+	w := &bytes.Buffer{}
+	sh := &shell{}
+	require.ErrorIs(t, sh.handleInput(w, "gosh-path-script", make(chan struct{}, 2)), ErrCommandNotFound)
+
+	input := fmt.Sprintf("export PATH=%s:$PATH; gosh-path-script; which gosh-path-script", dir)
+	require.NoError(t, sh.handleInput(w, input, make(chan struct{}, 2)))
+	want := path.Join(dir, "gosh-path-script")
+	require.Equal(t, "from "+want+"\n"+want+"\n", w.String())
+}
+
+func Test_runFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		script   string
+		args     []string
+		want     int
+		wantW    string
+		wantErrW string
+	}{
+		{name: "two commands", script: "echo one\necho two\n", wantW: "one\ntwo\n"},
+		{name: "arguments", script: "echo $# $1 $2\n", args: []string{"a", "b c"}, wantW: "2 a b c\n"},
+		{name: "last status", script: "echo one\nfalse\n", want: 1, wantW: "one\n", wantErrW: "exit status 1"},
+		{name: "missing file", want: 127, wantErrW: "no such file"},
+	}
+	for i, tt := range tests {
+		tt := tt
+		name := path.Join(dir, fmt.Sprintf("script%d.sh", i))
+		if tt.script != "" {
+			require.NoError(t, os.WriteFile(name, []byte(tt.script), 0o600))
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			errW := &bytes.Buffer{}
+			require.Equal(t, tt.want, runFile(name, tt.args, strings.NewReader(""), w, errW))
+			require.Equal(t, tt.wantW, w.String(), "a script should run without prompts")
+			if tt.wantErrW != "" {
+				require.Contains(t, errW.String(), tt.wantErrW)
+			} else {
+				require.Empty(t, errW.String())
+			}
+		})
+	}
+}
+
+func Test_runFileRead(t *testing.T) {
+	// not parallel: read sets environment variables.
+	t.Setenv("GOSH_A", "unchanged")
+	name := path.Join(t.TempDir(), "script.sh")
+	require.NoError(t, os.WriteFile(name, []byte("read GOSH_A\necho got $GOSH_A\necho last\n"), 0o600))
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	require.Equal(t, 0, runFile(name, nil, strings.NewReader("from stdin\n"), w, errW))
+	require.Equal(t, "got from stdin\nlast\n", w.String(), "read should take its line from stdin, not the script")
+	require.Empty(t, errW.String())
+}
+
+func Test_runLoopNoPrompt(t *testing.T) {
+	t.Parallel()
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	sh := &shell{noPrompt: true}
+	sh.runLoop(strings.NewReader("echo one\necho 'two\nthree'\n"), w, errW, make(chan struct{}, 2))
+
+	require.Empty(t, errW.String())
+	require.Equal(t, "one\ntwo\nthree\n", w.String())
+	require.NotContains(t, w.String(), "$ ")
+	require.NotContains(t, w.String(), continuationPrompt)
+}
+
+func Test_isTerminal(t *testing.T) {
+	t.Parallel()
+	f, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+	defer f.Close()
+	require.False(t, isTerminal(f), "a file is not a terminal")
+
+	r, pw, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer pw.Close()
+	require.False(t, isTerminal(r), "a pipe is not a terminal")
+}
+
+func Test_completions(t *testing.T) {
+	// not parallel: $PATH is replaced so only the test's commands are found.
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(bin, "gosh-tool"), nil, 0o700))
+	require.NoError(t, os.WriteFile(path.Join(bin, "gosh-data"), nil, 0o600))
+	t.Setenv("PATH", bin)
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(path.Join(dir, "nothing"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(dir, "notes.txt"), nil, 0o600))
+	require.NoError(t, os.WriteFile(path.Join(dir, ".notes"), nil, 0o600))
+
+	sh := &shell{aliases: map[string]string{"greet": "echo hello"}}
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{name: "executable in path", line: "gosh-", want: []string{"gosh-tool"}},
+		{name: "builtin", line: "al", want: []string{"alias"}},
+		{name: "alias", line: "gr", want: []string{"greet"}},
+		{name: "after a pipe", line: "echo hi | gosh-t", want: []string{"gosh-tool"}},
+		{name: "after a semicolon", line: "cd;un", want: []string{"unalias", "unset"}},
+		{name: "files", line: "cat " + dir + "/no", want: []string{dir + "/notes.txt", dir + "/nothing/"}},
+		{name: "hidden files", line: "cat " + dir + "/.no", want: []string{dir + "/.notes"}},
+		{name: "command with a slash", line: dir + "/noth", want: []string{dir + "/nothing/"}},
+		{name: "after a redirect", line: "echo hi >" + dir + "/notes", want: []string{dir + "/notes.txt"}},
+		{name: "no match", line: "no-such-command-gosh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, sh.completions(tt.line))
+		})
+	}
+}
+
+func Test_commonPrefix(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "ec", commonPrefix([]string{"echo", "ecran"}))
+	require.Equal(t, "same", commonPrefix([]string{"same"}))
+	require.Equal(t, "caf", commonPrefix([]string{"café", "cafè"}), "a character should not be cut in half")
+	require.Equal(t, "日本", commonPrefix([]string{"日本語", "日本人"}))
+}
+
+func Test_lineEditor(t *testing.T) {
+	t.Parallel()
+	complete := func(line string) []string {
+		for _, candidates := range [][]string{{"echo"}, {"gosh-a", "gosh-b"}, {"dir/"}} {
+			word := line[lastWordStart(line):]
+			if strings.HasPrefix(candidates[0], word) {
+				return candidates
+			}
+		}
+		return nil
+	}
+	history := func() []string { return []string{"echo one", "ls", "echo two"} }
+	tests := []struct {
+		name  string
+		input string
+		// typed reads the input a byte at a time, as keys are typed, rather than all at once as a paste is.
+		typed   bool
+		want    string
+		wantOut string
+		wantErr error
+	}{
+		{name: "typed", input: "echo hi\n", want: "echo hi\n", wantOut: "$ echo hi\n"},
+		{name: "carriage return", input: "echo hi\r", want: "echo hi\n"},
+		{name: "one candidate", input: "ec\thi\n", want: "echo hi\n"},
+		{name: "directory", input: "ls d\tx\n", want: "ls dir/x\n"},
+		{name: "common prefix", input: "go\ta\n", want: "gosh-a\n"},
+		{name: "candidates listed", input: "gosh-\t\n", want: "gosh-\n", wantOut: "$ gosh-\ngosh-a  gosh-b\n$ gosh-\n"},
+		{name: "no candidates", input: "x\t\n", want: "x\n", wantOut: "$ x\a\n"},
+		{name: "backspace", input: "echoo\x7f hi\n", want: "echo hi\n"},
+		{name: "backspace rune", input: "é\x7fe\n", want: "e\n"},
+		{name: "erase line", input: "rm -rf\x15echo\n", want: "echo\n"},
+		{name: "arrow keys skipped", input: "ec\x1b[Aho\n", want: "echo\n"},
+		{name: "end of input", input: "\x04", wantErr: io.EOF},
+		{name: "end of input ignored mid-line", input: "ec\x04ho\n", want: "echo\n"},
+		{name: "interrupt", input: "sleep 10\x03", wantErr: errInterrupted, wantOut: "$ sleep 10^C\n"},
+		{name: "search", input: "\x12echo\n", want: "echo two\n"},
+		{name: "search older", input: "\x12echo\x12\n", want: "echo one\n"},
+		{name: "search past the oldest", input: "\x12echo\x12\x12\n", want: "echo one\n"},
+		{name: "search backspace", input: "\x12lsx\x7f\n", want: "ls\n"},
+		{name: "search no match", input: "\x12zzz\n", want: "\n"},
+		{name: "search cancelled", input: "ec\x12ls\x1bpwd\n", typed: true, want: "pwd\n"},
+		{name: "search interrupted", input: "\x12ls\x03", wantErr: errInterrupted},
+		{
+			name:    "search shown",
+			input:   "\x12l\n",
+			want:    "ls\n",
+			wantOut: "$ \r\x1b[K(reverse-i-search)`': \r\x1b[K(reverse-i-search)`l': ls\r\x1b[K$ ls\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			out := &bytes.Buffer{}
+			var in io.Reader = strings.NewReader(tt.input)
+			if tt.typed {
+				in = iotest.OneByteReader(in)
+			}
+			e := &lineEditor{
+				in:       bufio.NewReader(in),
+				out:      out,
+				raw:      func() (func(), error) { return func() {}, nil },
+				complete: complete,
+				history:  history,
+			}
+			got, err := e.readLine("$ ")
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.want, got)
+			if tt.wantOut != "" {
+				require.Equal(t, tt.wantOut, out.String())
+			}
+		})
+	}
+}
+
+func Test_lineEditorNotRaw(t *testing.T) {
+	t.Parallel()
+	e := &lineEditor{
+		in:  bufio.NewReader(strings.NewReader("ec\thi\n")),
+		out: io.Discard,
+		raw: func() (func(), error) { return nil, errors.New("not a terminal") },
+	}
+	got, err := e.readLine("$ ")
+	require.NoError(t, err)
+	require.Equal(t, "ec\thi\n", got, "the line should be read as typed")
+}
+
+func Test_searchHistory(t *testing.T) {
+	t.Parallel()
+	history := []string{"echo one", "ls -l", "echo two", "cd /tmp"}
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{query: "echo", want: 2},
+		{query: "one", want: 0},
+		{query: "l", want: 1},
+		{query: "missing", want: -1},
+		{query: "", want: -1},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, searchHistory(history, tt.query), "searchHistory(%q)", tt.query)
+	}
+}
+
+func Test_read(t *testing.T) {
+	// not parallel: read sets environment variables.
+	tests := []struct {
+		name    string
+		input   string
+		line    string
+		want    map[string]string
+		wantW   string
+		wantErr bool
+	}{
+		{name: "one name", input: "read GOSH_A", line: "hello world\n", want: map[string]string{"GOSH_A": "hello world"}},
+		{
+			name:  "split across names",
+			input: "read GOSH_A GOSH_B",
+			line:  "  one two  three \n",
+			want:  map[string]string{"GOSH_A": "one", "GOSH_B": "two  three"},
+		},
+		{name: "names past the end", input: "read GOSH_A GOSH_B", line: "one\n", want: map[string]string{"GOSH_A": "one", "GOSH_B": ""}},
+		{name: "reply", input: "read", line: "hello\n", want: map[string]string{"REPLY": "hello"}},
+		{name: "prompt", input: "read -p 'name? ' GOSH_A", line: "gosh\n", want: map[string]string{"GOSH_A": "gosh"}, wantW: "name? "},
+		{name: "last line without a newline", input: "read GOSH_A", line: "last", want: map[string]string{"GOSH_A": "last"}},
+		{name: "end of input", input: "read GOSH_A", wantErr: true},
+		{name: "invalid name", input: "read 1A", line: "hello\n", wantErr: true},
+		{name: "prompt missing", input: "read -p", line: "hello\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"GOSH_A", "GOSH_B", "REPLY"} {
+				t.Setenv(name, "unchanged")
+			}
+			w := &bytes.Buffer{}
+			sh := &shell{input: bufio.NewReader(strings.NewReader(tt.line))}
+			err := sh.handleInput(w, tt.input, make(chan struct{}, 2))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantW, w.String())
+			for name, want := range tt.want {
+				require.Equal(t, want, os.Getenv(name), name)
+			}
+		})
+	}
+}
+
+func Test_runLoopRead(t *testing.T) {
+	// not parallel: read sets an environment variable.
+	t.Setenv("GOSH_READ", "")
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	sh := &shell{noPrompt: true}
+	sh.runLoop(strings.NewReader("read -p 'name? ' GOSH_READ\nhello world\necho \"[$GOSH_READ]\"\n"), w, errW, make(chan struct{}, 2))
+
+	require.Empty(t, errW.String())
+	require.Equal(t, "[hello world]\n", w.String(), "the line read should not be run, and the prompt is off")
+}
+
+func Test_assignment(t *testing.T) {
+	// not parallel: assignments set environment variables.
+	tests := []struct {
+		name    string
+		input   string
+		wantW   string
+		wantEnv string
+		wantErr error
+	}{
+		{name: "standalone", input: "GOSH_ASSIGN=bar", wantEnv: "bar"},
+		{name: "used afterwards", input: "GOSH_ASSIGN=bar; echo $GOSH_ASSIGN", wantW: "bar\n", wantEnv: "bar"},
+		{name: "quoted value", input: "GOSH_ASSIGN='a b' GOSH_OTHER=$GOSH_ASSIGN", wantEnv: "a b"},
+		{name: "prefix", input: "GOSH_ASSIGN=bar sh -c 'echo $GOSH_ASSIGN'", wantW: "bar\n", wantEnv: "before"},
+		{name: "prefix of a builtin", input: "GOSH_ASSIGN=bar env sh -c 'echo $GOSH_ASSIGN'", wantW: "bar\n", wantEnv: "before"},
+		{name: "prefix in a pipeline", input: "echo | GOSH_ASSIGN=bar sh -c 'echo $GOSH_ASSIGN'", wantW: "bar\n", wantEnv: "before"},
+		{name: "not a name", input: "1GOSH=bar", wantEnv: "before", wantErr: ErrCommandNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOSH_ASSIGN", "before")
+			t.Setenv("GOSH_OTHER", "")
+			w := &bytes.Buffer{}
+			err := (&shell{}).handleInput(w, tt.input, make(chan struct{}, 2))
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantW, w.String())
+			require.Equal(t, tt.wantEnv, os.Getenv("GOSH_ASSIGN"))
+		})
+	}
+}
+
+func Test_timePipeline(t *testing.T) {
+	t.Parallel()
+	times := regexp.MustCompile(`^\nreal\t\d+m\d+\.\d{3}s\nuser\t\d+m\d+\.\d{3}s\nsys\t\d+m\d+\.\d{3}s\n$`)
+	tests := []struct {
+		name    string
+		input   string
+		wantW   string
+		wantErr bool
+	}{
+		{name: "command", input: "time sh -c 'echo hi'", wantW: "hi\n"},
+		{name: "builtin", input: "time echo hi", wantW: "hi\n"},
+		{name: "pipeline", input: "time echo hi | tr a-z A-Z", wantW: "HI\n"},
+		{name: "failure", input: "time false", wantErr: true},
+		{name: "nothing", input: "time"},
+		{name: "after an assignment", input: "GOSH_TIME=1 time echo hi", wantW: "hi\n"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w, errW := &bytes.Buffer{}, &bytes.Buffer{}
+			err := (&shell{errW: errW}).handleInput(w, tt.input, make(chan struct{}, 2))
+			require.Equal(t, tt.wantErr, err != nil, "handleInput() error = %v", err)
+			require.Equal(t, tt.wantW, w.String(), "only the output should go to w")
+			require.Regexp(t, times, errW.String())
+		})
+	}
+}
+
+func Test_formatDuration(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "0m0.000s", formatDuration(0))
+	require.Equal(t, "0m0.250s", formatDuration(250*time.Millisecond))
+	require.Equal(t, "1m2.345s", formatDuration(62345*time.Millisecond))
+}
+
+func Test_clearScreen(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    string
+		wantW    string
+		wantErrW string
+		wantErr  bool
+	}{
+		{name: "forced", input: "clear -f", wantW: "\x1b[H\x1b[2J"},
+		{name: "cls", input: "cls -f", wantW: "\x1b[H\x1b[2J"},
+		{name: "not a terminal", input: "clear", wantErrW: "clear: output is not a terminal, use -f to clear it anyway\n"},
+		{name: "unknown argument", input: "clear -x", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w, errW := &bytes.Buffer{}, &bytes.Buffer{}
+			err := (&shell{errW: errW}).handleInput(w, tt.input, make(chan struct{}, 2))
+			require.Equal(t, tt.wantErr, err != nil, "handleInput() error = %v", err)
+			require.Equal(t, tt.wantW, w.String())
+			require.Equal(t, tt.wantErrW, errW.String())
+		})
+	}
+}
+
+func Test_directoryStack(t *testing.T) {
+	// not parallel: the stack changes the working directory and sets PWD and OLDPWD.
+	start, err := os.Getwd()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.Chdir(start)
+	})
+	t.Setenv("PWD", start)
+	t.Setenv("OLDPWD", "")
+	first, second := t.TempDir(), t.TempDir()
+
+	sh := &shell{}
+	steps := []struct {
+		input   string
+		wantW   string
+		wantDir string
+		wantOld string
+		wantErr bool
+	}{
+		{input: "pushd " + first, wantW: first + " " + start + "\n", wantDir: first, wantOld: start},
+		{input: "pushd " + second, wantW: second + " " + first + " " + start + "\n", wantDir: second, wantOld: first},
+		{input: "dirs", wantW: second + " " + first + " " + start + "\n", wantDir: second, wantOld: first},
+		{input: "pushd", wantW: first + " " + second + " " + start + "\n", wantDir: first, wantOld: second},
+		{input: "pushd", wantW: second + " " + first + " " + start + "\n", wantDir: second, wantOld: first},
+		{input: "popd", wantW: first + " " + start + "\n", wantDir: first, wantOld: second},
+		{input: "popd", wantW: start + "\n", wantDir: start, wantOld: first},
+		{input: "popd", wantErr: true, wantDir: start, wantOld: first},
+		{input: "pushd", wantErr: true, wantDir: start, wantOld: first},
+		{input: "pushd " + path.Join(first, "missing"), wantErr: true, wantDir: start, wantOld: first},
+		{input: "dirs", wantW: start + "\n", wantDir: start, wantOld: first},
+	}
+	for _, step := range steps {
+		w := &bytes.Buffer{}
+		err := sh.handleInput(w, step.input, make(chan struct{}, 2))
+		require.Equal(t, step.wantErr, err != nil, "%s: error = %v", step.input, err)
+		require.Equal(t, step.wantW, w.String(), step.input)
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		require.Equal(t, step.wantDir, wd, step.input)
+		require.Equal(t, step.wantDir, os.Getenv("PWD"), step.input)
+		require.Equal(t, step.wantOld, os.Getenv("OLDPWD"), step.input)
+	}
+
+	require.NoError(t, sh.handleInput(io.Discard, "pushd "+first, make(chan struct{}, 2)))
+	require.NoError(t, sh.handleInput(io.Discard, "dirs -c", make(chan struct{}, 2)))
+	require.Empty(t, sh.dirStack)
+}
+
+func Test_help(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		input string
+		// wantRows are the usage and summary of builtins listed in aligned columns.
+		wantRows [][2]string
+		wantW    string
+		wantErr  bool
+	}{
+		{
+			name:  "every builtin",
+			input: "help",
+			wantRows: [][2]string{
+				{"cd [dir | -]", "Change the working directory to dir, the home directory, or the previous directory."},
+				{"echo [-ne] [arg ...]", "Print the arguments separated by spaces."},
+				{"exit [status]", "Exit the shell with status, or with the last command's status."},
+				{"help [name ...]", "Print the builtins, or the help of each name."},
+			},
+			wantW: pipelineNote + "\n",
+		},
+		{
+			name:  "one builtin",
+			input: "help cd",
+			wantW: "cd: cd [dir | -]\n    Change the working directory to dir, the home directory, or the previous directory.\n",
+		},
+		{
+			name:  "time",
+			input: "help time",
+			wantW: "time: time [pipeline]\n    Run the pipeline, then print the real, user, and system time it took to stderr.\n",
+		},
+		{name: "alias of a builtin", input: "help .", wantW: ".: . file [arg ...]\n    Run the commands in file in this shell, as source does.\n"},
+		{name: "unknown", input: "help no-such-builtin", wantErr: true},
+		{name: "some unknown", input: "help no-such-builtin pwd", wantW: "pwd: pwd\n    Print the working directory.\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			w := &bytes.Buffer{}
+			err := (&shell{}).handleInput(w, tt.input, make(chan struct{}, 2))
+			require.Equal(t, tt.wantErr, err != nil, "handleInput() error = %v", err)
+			if tt.wantRows == nil {
+				require.Equal(t, tt.wantW, w.String())
+			} else {
+				require.True(t, strings.HasSuffix(w.String(), tt.wantW), "the list should end with %q: %q", tt.wantW, w)
+			}
+			for _, row := range tt.wantRows {
+				require.Regexp(t, "(?m)^"+regexp.QuoteMeta(row[0])+" +"+regexp.QuoteMeta(row[1])+"$", w.String())
+			}
+		})
+	}
+}
+
+func Test_hereDocuments(t *testing.T) {
+	t.Setenv("GOSH_HERE", "there")
+	tests := []struct {
+		name    string
+		input   string
+		wantW   string
+		wantErr error
+	}{
+		{name: "here-string", input: `cat <<< "hi $GOSH_HERE"`, wantW: "hi there\n"},
+		{name: "here-string in a pipeline", input: "cat <<< hello | tr a-z A-Z", wantW: "HELLO\n"},
+		{name: "here-document", input: "cat <<EOF\nhi $GOSH_HERE\n  'quoted' \\$GOSH_HERE\nEOF", wantW: "hi there\n  'quoted' $GOSH_HERE\n"},
+		{name: "quoted delimiter", input: "cat <<'EOF'\nhi $GOSH_HERE\nEOF\n", wantW: "hi $GOSH_HERE\n"},
+		{name: "command after", input: "cat <<EOF; echo after\nbody\nEOF\n", wantW: "body\nafter\n"},
+		{name: "empty", input: "cat <<EOF\nEOF", wantW: ""},
+		{name: "unterminated", input: "cat <<EOF\nbody\n", wantErr: errUnterminated},
+		{name: "missing delimiter", input: "cat <<", wantErr: ErrSyntax},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			err := (&shell{}).handleInput(w, tt.input, make(chan struct{}, 2))
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantW, w.String())
+		})
+	}
+}
+
+func Test_runLoopHereDocument(t *testing.T) {
+	t.Parallel()
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	sh := &shell{}
+	sh.runLoop(strings.NewReader("cat <<END\none\ntwo\nEND\necho done\n"), w, errW, make(chan struct{}, 2))
+
+	require.Empty(t, errW.String())
+	require.Contains(t, w.String(), "$ > > > one\ntwo\n")
+	require.Contains(t, w.String(), "$ done\n")
+}
+
+func Test_sourceHereDocument(t *testing.T) {
+	t.Parallel()
+	file := path.Join(t.TempDir(), "heredoc.sh")
+	require.NoError(t, os.WriteFile(file, []byte("cat <<EOF\nbody $1\nEOF\necho 'not\nclosed\n"), 0o600))
+	w := &bytes.Buffer{}
+	err := (&shell{}).handleInput(w, "source "+file+" arg", make(chan struct{}, 2))
+	require.Equal(t, "body arg\n", w.String())
+	require.ErrorContains(t, err, file+":4: "+errUnterminated.Error())
+}
+
+func Test_setOptions(t *testing.T) {
+	t.Parallel()
+	sh := &shell{}
+	w := &bytes.Buffer{}
+	require.NoError(t, sh.handleInput(w, "set -o", make(chan struct{}, 2)))
+	require.Equal(t, "noclobber      off\nxtrace         off\n", w.String())
+
+	w.Reset()
+	require.NoError(t, sh.handleInput(w, "set -o noclobber -o xtrace; set +o xtrace; set -o", make(chan struct{}, 2)))
+	require.Equal(t, "noclobber      on\nxtrace         off\n", w.String())
+	require.Equal(t, options{noclobber: true}, sh.options)
+
+	require.Error(t, sh.handleInput(io.Discard, "set -o no-such-option", make(chan struct{}, 2)))
+	require.Error(t, sh.handleInput(io.Discard, "set -x xtrace", make(chan struct{}, 2)))
+	require.Error(t, sh.handleInput(io.Discard, "set +o", make(chan struct{}, 2)))
+	require.Equal(t, options{noclobber: true}, sh.options, "a failed set should leave the options alone")
+}
+
+func Test_xtrace(t *testing.T) {
+	t.Setenv("GOSH_TRACE", "value")
+	w := &bytes.Buffer{}
+	errW := &bytes.Buffer{}
+	sh := &shell{noPrompt: true}
+	sh.runLoop(strings.NewReader("set -o xtrace\necho $GOSH_TRACE > /dev/null\nGOSH_X=1 true | cat\nset +o xtrace\necho off\n"), w, errW, make(chan struct{}, 2))
+	require.Equal(t, "off\n", w.String())
+	require.Equal(t, "+ echo value\n+ GOSH_X=1 true\n+ cat\n+ set +o xtrace\n", errW.String())
+}
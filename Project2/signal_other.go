@@ -0,0 +1,27 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// signals are the signals kill can send by name, without their SIG prefix.
+// Without job control there are none to stop or continue a process.
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+}
+
+// jobStates is empty, as no signal stops or continues a job here.
+var jobStates = map[syscall.Signal]bool{}
+
+// continueProcess is only implemented for Unix, where jobs can be stopped in the first place.
+func continueProcess(*os.Process) error {
+	return errors.New("continuing a stopped process is not supported on this system")
+}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// signals are the signals kill can send by name, without their SIG prefix.
+var signals = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+	"CONT": syscall.SIGCONT,
+	"STOP": syscall.SIGSTOP,
+	"TSTP": syscall.SIGTSTP,
+}
+
+// jobStates maps the signals that stop or continue a process to whether a job sent one is left stopped.
+var jobStates = map[syscall.Signal]bool{
+	syscall.SIGSTOP: true,
+	syscall.SIGTSTP: true,
+	syscall.SIGCONT: false,
+}
+
+// continueProcess sends SIGCONT to p, so it carries on from where it was stopped.
+func continueProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGCONT)
+}
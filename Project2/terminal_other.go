@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw is only implemented for Linux and the BSDs, macOS among them; elsewhere the line editor reads lines as typed.
+func makeRaw(*os.File) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this system")
+}